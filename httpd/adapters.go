@@ -0,0 +1,110 @@
+package httpd
+
+import (
+	"context"
+	"net/http"
+)
+
+type paramsKey struct{}
+
+// ParamsFromContext returns request params added to a context by adapters, or nil.
+func ParamsFromContext(ctx context.Context) Params {
+	params, _ := ctx.Value(paramsKey{}).(Params)
+	return params
+}
+
+// WrapHandler adapts an http.Handler to a Handler.
+// The handler receives a request with the handler context and the params, see ParamsFromContext.
+func WrapHandler(h http.Handler) Handler {
+	if h == nil {
+		panic("router: Nil handler")
+	}
+
+	return func(ctx context.Context, req *Req, resp *Resp) error {
+		h.ServeHTTP(resp, req.httpRequest(ctx))
+		return nil
+	}
+}
+
+// WrapMiddleware adapts net/http middleware to a Middleware.
+//
+// The next handler receives the request and the response writer passed by the middleware,
+// the params are preserved. When the middleware wraps the response writer, the outer Resp
+// still accounts its status and written bytes because all writes pass through it.
+func WrapMiddleware(m func(http.Handler) http.Handler) Middleware {
+	if m == nil {
+		panic("router: Nil middleware")
+	}
+
+	return func(ctx context.Context, req *Req, resp *Resp, next Handler) error {
+		var err error
+		h := m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req1 := req
+			if r != req.Request {
+				req1 = newReq(req.Router, r, req.Params)
+				req1.bodyPolicy = req.bodyPolicy
+				defer req1.cleanup()
+			}
+
+			resp1 := resp
+			if w != http.ResponseWriter(resp) {
+				resp1 = newResp(resp.Router, w, r)
+			}
+
+			err = next(r.Context(), req1, resp1)
+		}))
+
+		h.ServeHTTP(resp, req.httpRequest(ctx))
+		return err
+	}
+}
+
+// ToHTTPHandler adapts a Handler to an http.Handler, i.e. to mount it in an http.ServeMux.
+// The params are taken from the request context, see ParamsFromContext, errors are handled by the router.
+func ToHTTPHandler(router *Router, h Handler) http.Handler {
+	if router == nil {
+		panic("router: Nil router")
+	}
+	if h == nil {
+		panic("router: Nil handler")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		params := ParamsFromContext(ctx)
+		if params == nil {
+			params = Params{}
+		}
+
+		req := newReq(router, r, params)
+		resp := newResp(router, w, r)
+		defer req.cleanup()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				a, ok := recovered.(abort)
+				if !ok {
+					panic(recovered)
+				}
+
+				handlers, _ := router.errorHandlers(nil, "")
+				handlers.Error(ctx, req, resp, a.err)
+			}
+		}()
+
+		if err := h(ctx, req, resp); err != nil {
+			handlers, _ := router.errorHandlers(nil, "")
+			handlers.Error(ctx, req, resp, err)
+		}
+	})
+}
+
+// httpRequest returns the request with a context which contains the params.
+func (r *Req) httpRequest(ctx context.Context) *http.Request {
+	if ParamsFromContext(ctx) == nil {
+		ctx = context.WithValue(ctx, paramsKey{}, r.Params)
+	}
+	if ctx == r.Request.Context() {
+		return r.Request
+	}
+	return r.Request.WithContext(ctx)
+}
@@ -0,0 +1,72 @@
+package httpd
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapHandler(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/users/:id", WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		params := ParamsFromContext(req.Context())
+		w.Write([]byte("user " + params["id"]))
+	})))
+
+	w := serve(r, http.MethodGet, "/users/123")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user 123", w.Body.String())
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	type key struct{}
+
+	var status int
+	var total int64
+	r := NewRouter(nil)
+	r.Middleware("/", func(ctx context.Context, req *Req, resp *Resp, next Handler) error {
+		err := next(ctx, req, resp)
+		status, total = resp.Status, resp.TotalBytes
+		return err
+	})
+	r.Middleware("/", WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := context.WithValue(req.Context(), key{}, "value")
+			next.ServeHTTP(&headerWriter{w}, req.WithContext(ctx))
+		})
+	}))
+	r.GET("/users/:id", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.TextStatus(req.Param("id")+" "+ctx.Value(key{}).(string), http.StatusCreated)
+	})
+
+	w := serve(r, http.MethodGet, "/users/123")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "123 value", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("X-Wrapped"))
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, int64(9), total)
+}
+
+func TestToHTTPHandler(t *testing.T) {
+	r := NewRouter(nil)
+	h := ToHTTPHandler(r, func(ctx context.Context, req *Req, resp *Resp) error {
+		return NewBadRequestError("invalid")
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+
+	w := serveHTTP(mux, http.MethodGet, "/")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+type headerWriter struct {
+	http.ResponseWriter
+}
+
+func (w *headerWriter) WriteHeader(status int) {
+	w.Header().Set("X-Wrapped", "true")
+	w.ResponseWriter.WriteHeader(status)
+}
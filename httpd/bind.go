@@ -0,0 +1,433 @@
+package httpd
+
+import (
+	"encoding"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bindSources are struct tags of request value sources in the order of their precedence.
+var bindSources = []string{"path", "query", "form", "header"}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// bindTypes contains checked bind struct types and their plans, see checkBindType.
+var bindTypes sync.Map // map[reflect.Type]*bindPlan
+
+// bindPlan lists untagged nested struct fields which contain bind tags,
+// a nil plan means a struct has no bind tags.
+type bindPlan struct {
+	nested []bindNested
+}
+
+type bindNested struct {
+	index int
+	typ   reflect.Type // Struct type of a struct or a struct pointer field.
+	plan  *bindPlan
+}
+
+// Bind binds a request into a struct pointer, i.e.
+//
+//	type Request struct {
+//		ID     int64    `path:"id"`
+//		Limit  int      `query:"limit" default:"10"`
+//		Tags   []string `query:"tag"`
+//		Tenant string   `header:"X-Tenant"`
+//		Name   string   `json:"name"`
+//	}
+//
+// Defaults are set first, then the body is decoded by its content type, JSON and XML bodies are decoded
+// into the struct, form bodies are bound with form tags, then path, query, form and header values are bound
+// by their tags. Fields with source tags are bound only from their sources, decoded body values
+// are reset to their defaults. Missing values are skipped, repeated values are bound to slices, untagged nested structs
+// and struct pointers with bind tags are bound recursively, nil struct pointers are allocated only when values
+// or defaults are bound into them, nested structs of an enclosing struct type are skipped. Fields can be strings, bools, numbers, durations,
+// RFC 3339 times, encoding.TextUnmarshalers, pointers and slices of them.
+//
+// Invalid values are returned as a single BadRequestError which lists all invalid fields,
+// unsupported body content types are returned as 415 Errors, JSON and XML bodies are decoded by the body policy,
+// see BodyPolicy. Bound structs are validated, see Validate.
+//
+// Bind panics on unsupported tagged field types and invalid defaults, each struct type is checked
+// once when it is first bound.
+func (r *Req) Bind(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("router: Bind destination must be a non-nil struct pointer, got %T", dst))
+	}
+	plan := checkBindType(v.Elem().Type())
+
+	b := binder{req: r}
+	b.setDefaults(v.Elem(), plan)
+	if err := r.decodeBody(dst); err != nil {
+		return err
+	}
+
+	b.resetTagged(v.Elem(), plan)
+	b.bindStruct(v.Elem(), plan)
+	if len(b.fields) > 0 {
+		return NewFieldsError(b.fields...)
+	}
+	return Validate(dst)
+}
+
+// decodeBody decodes a JSON or XML body into a destination, parses form bodies, skips empty bodies.
+func (r *Req) decodeBody(dst interface{}) error {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return r.decodeJSON(dst)
+
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		body, err := r.body()
+		if err != nil {
+			return err
+		}
+		if err := xml.NewDecoder(body).Decode(dst); err != nil {
+			if bodyErr := bodyError(err, r.bodyPolicy.maxSize()); bodyErr != nil {
+				return bodyErr
+			}
+			return NewBadRequestError(err.Error())
+		}
+		return nil
+
+	case mediaType == "application/x-www-form-urlencoded", mediaType == "multipart/form-data":
+		body, err := r.body()
+		if err != nil {
+			return err
+		}
+
+		// Parse urlencoded bodies separately, ParseMultipartForm ignores their errors.
+		r.Body = io.NopCloser(body)
+		if mediaType == "multipart/form-data" {
+			err = r.ParseMultipartForm(32 << 20)
+		} else {
+			err = r.ParseForm()
+		}
+		if err != nil {
+			if bodyErr := bodyError(err, r.bodyPolicy.maxSize()); bodyErr != nil {
+				return bodyErr
+			}
+			return NewBadRequestError(err.Error())
+		}
+		return nil
+	}
+
+	return NewError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+		fmt.Sprintf("Unsupported content type %q", contentType))
+}
+
+type binder struct {
+	req    *Req
+	fields []FieldError
+	bound  int // Number of bound values.
+}
+
+// setDefaults sets fields to their default tag values.
+func (b *binder) setDefaults(v reflect.Value, plan *bindPlan) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if def, ok := f.Tag.Lookup("default"); ok {
+			b.bindValue(v.Field(i), Value{Source: "default", Name: f.Name, Raw: def, Exists: true})
+		}
+	}
+	b.bindNested(v, plan, b.setDefaults)
+}
+
+// resetTagged resets source tagged fields to their default tag values or zero values,
+// i.e. values decoded from a body.
+func (b *binder) resetTagged(v reflect.Value, plan *bindPlan) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || !sourceTagged(f) {
+			continue
+		}
+
+		field := v.Field(i)
+		field.Set(reflect.Zero(f.Type))
+		if def, ok := f.Tag.Lookup("default"); ok {
+			b.bindValue(field, Value{Source: "default", Name: f.Name, Raw: def, Exists: true})
+		}
+	}
+	b.bindNested(v, plan, b.resetTagged)
+}
+
+// bindStruct binds struct fields by their source tags, collects invalid fields.
+func (b *binder) bindStruct(v reflect.Value, plan *bindPlan) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		for _, source := range bindSources {
+			name, ok := f.Tag.Lookup(source)
+			if !ok || name == "" || name == "-" {
+				continue
+			}
+			if values := b.values(source, name); len(values) > 0 {
+				b.bindValues(v.Field(i), source, name, values)
+				break
+			}
+		}
+	}
+	b.bindNested(v, plan, b.bindStruct)
+}
+
+// bindNested binds nested struct fields from a plan, allocates nil struct pointers
+// only when values are bound into them.
+func (b *binder) bindNested(v reflect.Value, plan *bindPlan, bind func(reflect.Value, *bindPlan)) {
+	if plan == nil {
+		return
+	}
+
+	for _, n := range plan.nested {
+		field := v.Field(n.index)
+		switch {
+		case field.Kind() != reflect.Ptr:
+			bind(field, n.plan)
+		case !field.IsNil():
+			bind(field.Elem(), n.plan)
+		default:
+			s := reflect.New(n.typ)
+			bound := b.bound
+			bind(s.Elem(), n.plan)
+			if b.bound > bound {
+				field.Set(s)
+			}
+		}
+	}
+}
+
+// values returns request values from a source.
+func (b *binder) values(source string, name string) []string {
+	switch source {
+	case "path":
+		if v, ok := b.req.Params[name]; ok {
+			return []string{v}
+		}
+	case "query":
+		return b.req.Query().Values[name]
+	case "form":
+		return b.req.PostForm[name]
+	case "header":
+		return b.req.Header.Values(name)
+	}
+	return nil
+}
+
+func (b *binder) bindValues(field reflect.Value, source string, name string, values []string) {
+	if source == "path" {
+		source = "param"
+	}
+
+	if field.Kind() == reflect.Slice && !isTextUnmarshaler(field) {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, raw := range values {
+			b.bindValue(slice.Index(i), Value{Source: source, Name: name, Raw: raw, Exists: true})
+		}
+		field.Set(slice)
+		return
+	}
+
+	b.bindValue(field, Value{Source: source, Name: name, Raw: values[0], Exists: true})
+}
+
+// bindValue converts and sets a value, panics on unsupported field types.
+func (b *binder) bindValue(field reflect.Value, v Value) {
+	b.bound++
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+
+	var err error
+	switch {
+	case field.Type() == timeType:
+		var t time.Time
+		if t, err = v.Time(); err == nil {
+			field.Set(reflect.ValueOf(t))
+		}
+
+	case field.Type() == durationType:
+		var d time.Duration
+		if d, err = v.Duration(); err == nil {
+			field.SetInt(int64(d))
+		}
+
+	case isTextUnmarshaler(field):
+		u := field.Addr().Interface().(encoding.TextUnmarshaler)
+		if u.UnmarshalText([]byte(v.Raw)) != nil {
+			err = v.invalid("a valid value")
+		}
+
+	default:
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(v.Raw)
+
+		case reflect.Bool:
+			var x bool
+			if x, err = v.Bool(); err == nil {
+				field.SetBool(x)
+			}
+
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			var x int64
+			if x, err = v.parseInt(field.Type().Bits()); err == nil {
+				field.SetInt(x)
+			}
+
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			var x uint64
+			if x, err = v.parseUint(field.Type().Bits()); err == nil {
+				field.SetUint(x)
+			}
+
+		case reflect.Float32, reflect.Float64:
+			var x float64
+			if x, err = v.Float64(); err == nil {
+				field.SetFloat(x)
+			}
+
+		default:
+			panic(fmt.Sprintf("router: Unsupported bind field type %v", field.Type()))
+		}
+	}
+
+	if bad, ok := err.(BadRequestError); ok {
+		b.fields = append(b.fields, bad.Fields...)
+	}
+}
+
+// checkBindType panics on unsupported source tagged field types and invalid defaults in a struct type,
+// returns the type bind plan.
+func checkBindType(t reflect.Type) *bindPlan {
+	if plan, ok := bindTypes.Load(t); ok {
+		return plan.(*bindPlan)
+	}
+
+	plan := checkBindFields(t, t, make(map[reflect.Type]bool))
+	bindTypes.Store(t, plan)
+	return plan
+}
+
+// checkBindFields checks struct fields and returns a bind plan, skips nested structs of enclosing types
+// in a path to support recursive types.
+func checkBindFields(root reflect.Type, t reflect.Type, path map[reflect.Type]bool) *bindPlan {
+	path[t] = true
+	defer delete(path, t)
+
+	plan := &bindPlan{}
+	tagged := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		def, ok := f.Tag.Lookup("default")
+		if ok {
+			b := binder{}
+			b.bindValue(reflect.New(f.Type).Elem(), Value{Source: "default", Name: f.Name, Raw: def, Exists: true})
+			if len(b.fields) > 0 {
+				panic(fmt.Sprintf("router: Invalid bind default in %v, %v", root, NewFieldsError(b.fields...).Text))
+			}
+			tagged = true
+		}
+
+		switch {
+		case sourceTagged(f):
+			if !bindableType(f.Type) {
+				panic(fmt.Sprintf("router: Unsupported bind field type %v", f.Type))
+			}
+			tagged = true
+		case !ok:
+			nested, ok := nestedStructType(f.Type)
+			if !ok || path[nested] {
+				continue
+			}
+			if p := checkBindFields(root, nested, path); p != nil {
+				plan.nested = append(plan.nested, bindNested{index: i, typ: nested, plan: p})
+			}
+		}
+	}
+
+	if !tagged && len(plan.nested) == 0 {
+		return nil
+	}
+	return plan
+}
+
+// sourceTagged returns true when a field has a bind source tag, see bindSources.
+func sourceTagged(f reflect.StructField) bool {
+	for _, source := range bindSources {
+		if name, ok := f.Tag.Lookup(source); ok && name != "" && name != "-" {
+			return true
+		}
+	}
+	return false
+}
+
+// bindableType returns true when request values can be bound to a type, see bindValues.
+func bindableType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice && !reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType || t == durationType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// nestedStructType returns a struct type of a struct or a struct pointer which can be bound recursively.
+func nestedStructType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return nil, false
+	}
+	return t, true
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func isTextUnmarshaler(field reflect.Value) bool {
+	return field.CanAddr() && reflect.PtrTo(field.Type()).Implements(textUnmarshalerType)
+}
@@ -0,0 +1,203 @@
+package httpd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bindPage struct {
+	Limit  int `query:"limit" default:"10"`
+	Offset int `query:"offset"`
+}
+
+type bindRequest struct {
+	ID      int64         `path:"id"`
+	Tags    []string      `query:"tag"`
+	Since   *time.Time    `query:"since"`
+	Timeout time.Duration `query:"timeout" default:"1s"`
+	Tenant  string        `header:"X-Tenant"`
+	Name    string        `json:"name"`
+	Age     int           `json:"age" default:"18"`
+	Page    bindPage
+}
+
+func TestReq_Bind(t *testing.T) {
+	httpReq := newBodyRequest(http.MethodPost, "/users/1?tag=a&tag=b&since=2020-01-02T03:04:05Z&offset=20",
+		"application/json", `{"name": "John"}`)
+	httpReq.Header.Set("X-Tenant", "acme")
+
+	req := newReq(NewRouter(nil), httpReq, Params{"id": "1"})
+	dst := bindRequest{}
+	err := req.Bind(&dst)
+	assert.Nil(t, err)
+
+	since := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, bindRequest{
+		ID:      1,
+		Tags:    []string{"a", "b"},
+		Since:   &since,
+		Timeout: time.Second,
+		Tenant:  "acme",
+		Name:    "John",
+		Age:     18,
+		Page:    bindPage{Limit: 10, Offset: 20},
+	}, dst)
+}
+
+func TestReq_Bind__should_bind_form_body(t *testing.T) {
+	form := url.Values{"name": {"John"}, "ids": {"1", "2"}}
+	httpReq := newBodyRequest(http.MethodPost, "/", "application/x-www-form-urlencoded", form.Encode())
+
+	dst := struct {
+		Name string  `form:"name"`
+		IDs  []int64 `form:"ids"`
+	}{}
+	err := newReq(NewRouter(nil), httpReq, nil).Bind(&dst)
+	assert.Nil(t, err)
+	assert.Equal(t, "John", dst.Name)
+	assert.Equal(t, []int64{1, 2}, dst.IDs)
+}
+
+func TestReq_Bind__should_limit_form_body_size(t *testing.T) {
+	r := NewRouter(nil)
+	r.SetBodyPolicy(BodyPolicy{MaxSize: 32})
+
+	dst := struct {
+		Name string `form:"name"`
+	}{}
+
+	form := url.Values{"name": {strings.Repeat("a", 64)}}
+	httpReq := newBodyRequest(http.MethodPost, "/", "application/x-www-form-urlencoded", form.Encode())
+	httpReq.ContentLength = -1
+	err := newReq(r, httpReq, nil).Bind(&dst)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*Error).Status)
+
+	httpReq = newMultipartRequest("/", "name", strings.Repeat("a", 64))
+	err = newReq(r, httpReq, nil).Bind(&dst)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*Error).Status)
+
+	httpReq = newMultipartRequest("/", "name", strings.Repeat("a", 64))
+	httpReq.ContentLength = -1
+	err = newReq(r, httpReq, nil).Bind(&dst)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*Error).Status)
+}
+
+func TestReq_Bind__should_not_bind_body_to_source_tagged_fields(t *testing.T) {
+	httpReq := newBodyRequest(http.MethodPost, "/users/1", "application/json",
+		`{"ID": 2, "Tenant": "evil", "Timeout": 5, "name": "John", "Page": {"Limit": 1000, "Offset": 5}}`)
+
+	req := newReq(NewRouter(nil), httpReq, Params{"id": "1"})
+	dst := bindRequest{}
+	err := req.Bind(&dst)
+	assert.Nil(t, err)
+	assert.Equal(t, bindRequest{
+		ID:      1,
+		Timeout: time.Second,
+		Name:    "John",
+		Age:     18,
+		Page:    bindPage{Limit: 10},
+	}, dst)
+}
+
+type bindNode struct {
+	ID   int       `query:"id"`
+	Name string    `json:"name"`
+	Next *bindNode `json:"next"`
+}
+
+type bindAddress struct {
+	City string `query:"city"`
+}
+
+type bindProfile struct {
+	Name    string       `json:"name"`
+	Address *bindAddress `json:"address"`
+	Home    *bindPage    `json:"home"`
+}
+
+func TestReq_Bind__should_bind_recursive_types(t *testing.T) {
+	httpReq := newBodyRequest(http.MethodPost, "/?id=1", "application/json",
+		`{"name": "a", "next": {"name": "b"}}`)
+
+	req := newReq(NewRouter(nil), httpReq, nil)
+	dst := bindNode{}
+	err := req.Bind(&dst)
+	assert.Nil(t, err)
+	assert.Equal(t, bindNode{ID: 1, Name: "a", Next: &bindNode{Name: "b"}}, dst)
+}
+
+func TestReq_Bind__should_allocate_pointers_only_for_bound_values(t *testing.T) {
+	httpReq := newBodyRequest(http.MethodPost, "/", "application/json", `{"name": "John"}`)
+
+	req := newReq(NewRouter(nil), httpReq, nil)
+	dst := bindProfile{}
+	err := req.Bind(&dst)
+	assert.Nil(t, err)
+	assert.Nil(t, dst.Address)
+	assert.Equal(t, &bindPage{Limit: 10}, dst.Home)
+
+	httpReq = newBodyRequest(http.MethodPost, "/?city=Berlin", "application/json", `{"name": "John"}`)
+	req = newReq(NewRouter(nil), httpReq, nil)
+	dst = bindProfile{}
+	err = req.Bind(&dst)
+	assert.Nil(t, err)
+	assert.Equal(t, &bindAddress{City: "Berlin"}, dst.Address)
+}
+
+func TestReq_Bind__should_check_struct_types(t *testing.T) {
+	req := newReq(NewRouter(nil), newTestRequest(http.MethodGet, "/"), nil)
+
+	assert.PanicsWithValue(t, "router: Unsupported bind field type map[string]string", func() {
+		req.Bind(&struct {
+			Filter map[string]string `query:"filter"`
+		}{})
+	})
+	assert.PanicsWithValue(t, "router: Unsupported bind field type []string", func() {
+		req.Bind(&struct {
+			Tags []string `default:"a,b"`
+		}{})
+	})
+	assert.PanicsWithValue(t, `router: Invalid bind default in struct { Page httpd.bindInvalidDefault }, `+
+		`Bad request: Invalid default "Limit", must be an integer`, func() {
+		req.Bind(&struct {
+			Page bindInvalidDefault
+		}{})
+	})
+}
+
+type bindInvalidDefault struct {
+	Limit int `query:"limit" default:"ten"`
+}
+
+func TestReq_Bind__should_list_invalid_fields(t *testing.T) {
+	httpReq := newTestRequest(http.MethodGet, "/users/abc?since=yesterday&limit=x")
+	req := newReq(NewRouter(nil), httpReq, Params{"id": "abc"})
+
+	err := req.Bind(&bindRequest{})
+	assert.Equal(t, NewFieldsError(
+		FieldError{Field: "id", In: "param", Code: "invalid", Message: "must be an integer"},
+		FieldError{Field: "since", In: "query", Code: "invalid", Message: "must be an RFC 3339 time"},
+		FieldError{Field: "limit", In: "query", Code: "invalid", Message: "must be an integer"},
+	), err)
+	assert.EqualError(t, err, `Bad request: Invalid param "id", must be an integer; `+
+		`Invalid query "since", must be an RFC 3339 time; Invalid query "limit", must be an integer`)
+}
+
+func TestReq_Bind__should_reject_unsupported_content_types(t *testing.T) {
+	httpReq := newBodyRequest(http.MethodPost, "/", "application/octet-stream", "data")
+
+	err := newReq(NewRouter(nil), httpReq, nil).Bind(&bindRequest{})
+	assert.Equal(t, http.StatusUnsupportedMediaType, err.(*Error).Status)
+}
+
+func newBodyRequest(method string, target string, contentType string, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
@@ -0,0 +1,245 @@
+package httpd
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxBodySize is the default max size of decoded request bodies, see BodyPolicy.
+const DefaultMaxBodySize = 1 << 20
+
+// BodyPolicy configures request body decoding in Req.DecodeJSON and Req.Bind.
+type BodyPolicy struct {
+	MaxSize int64 // Max body size in bytes after decompression, zero is DefaultMaxBodySize, negative is unlimited.
+	Strict  bool  // Reject unknown JSON fields and data after the JSON value.
+}
+
+// SetBodyPolicy sets a router body policy, it can be overridden in subtrees, see WithBodyPolicy.
+func (r *Router) SetBodyPolicy(policy BodyPolicy) {
+	r.load().checkFrozen()
+	r.body = policy
+}
+
+// WithBodyPolicy returns middleware which overrides the router body policy, i.e. a larger body size
+// for uploads.
+//
+//	router.Middleware("/uploads", httpd.WithBodyPolicy(httpd.BodyPolicy{MaxSize: 100 << 20}))
+func WithBodyPolicy(policy BodyPolicy) Middleware {
+	return func(ctx context.Context, req *Req, resp *Resp, next Handler) error {
+		req.bodyPolicy = policy
+		return next(ctx, req, resp)
+	}
+}
+
+func (p BodyPolicy) maxSize() int64 {
+	switch {
+	case p.MaxSize == 0:
+		return DefaultMaxBodySize
+	case p.MaxSize < 0:
+		return 0
+	}
+	return p.MaxSize
+}
+
+// body returns a request body reader which decompresses gzip bodies and limits their size,
+// returns 413 and 415 errors.
+func (r *Req) body() (io.Reader, error) {
+	var body io.Reader = r.Body
+	if r.Body == nil {
+		body = http.NoBody
+	}
+
+	limit := r.bodyPolicy.maxSize()
+	switch encoding := strings.ToLower(r.Header.Get("Content-Encoding")); encoding {
+	case "", "identity":
+		if limit > 0 && r.ContentLength > limit {
+			return nil, newBodyTooLargeError(limit)
+		}
+
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, NewBadRequestError("Invalid gzip body")
+		}
+		body = gz
+
+	default:
+		return nil, NewError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			fmt.Sprintf("Unsupported content encoding %q", encoding))
+	}
+
+	if limit > 0 {
+		body = http.MaxBytesReader(nil, io.NopCloser(body), limit)
+	}
+	return body, nil
+}
+
+// decodeJSON decodes a JSON body, returns errors with line and column positions.
+func (r *Req) decodeJSON(dst interface{}) error {
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return NewError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			fmt.Sprintf("Unsupported content type %q, expected application/json", contentType))
+	}
+
+	body, err := r.body()
+	if err != nil {
+		return err
+	}
+
+	reader := &offsetReader{r: body}
+	dec := json.NewDecoder(reader)
+	if r.bodyPolicy.Strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(dst); err != nil {
+		return r.jsonError(err, reader, dec)
+	}
+
+	if r.bodyPolicy.Strict {
+		switch _, err := dec.Token(); {
+		case err == io.EOF:
+		case err != nil:
+			return r.jsonError(err, reader, dec)
+		default:
+			line, column := reader.position(dec.InputOffset())
+			return NewBadRequestError(fmt.Sprintf(
+				"Invalid JSON at line %d, column %d, unexpected data after the value", line, column))
+		}
+	}
+	return nil
+}
+
+// jsonError returns a bad request error with a line and column position, or a body size error.
+func (r *Req) jsonError(err error, reader *offsetReader, dec *json.Decoder) error {
+	if bodyErr := bodyError(err, r.bodyPolicy.maxSize()); bodyErr != nil {
+		return bodyErr
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == io.EOF:
+		return NewBadRequestError("Empty JSON body")
+
+	case err == io.ErrUnexpectedEOF:
+		return NewBadRequestError("Invalid JSON, unexpected end of body")
+
+	case errors.As(err, &syntaxErr):
+		line, column := reader.position(syntaxErr.Offset)
+		return NewBadRequestError(fmt.Sprintf("Invalid JSON at line %d, column %d, %v", line, column, syntaxErr))
+
+	case errors.As(err, &typeErr):
+		line, column := reader.position(typeErr.Offset)
+		if typeErr.Field == "" {
+			return NewBadRequestError(fmt.Sprintf("Invalid JSON at line %d, column %d, expected %v",
+				line, column, jsonType(typeErr.Type)))
+		}
+		return positionedFieldsError(line, column, FieldError{
+			Field:   typeErr.Field,
+			In:      "body",
+			Code:    "invalid",
+			Message: "must be " + jsonType(typeErr.Type),
+		})
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		name, unquoteErr := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		if unquoteErr != nil {
+			break
+		}
+		line, column := reader.position(dec.InputOffset())
+		return positionedFieldsError(line, column, FieldError{
+			Field:   name,
+			In:      "body",
+			Code:    "unknown",
+			Message: "is not allowed",
+		})
+	}
+	return NewBadRequestError(err.Error())
+}
+
+// bodyError returns a 413 error when a body exceeds its max size, or nil.
+func bodyError(err error, limit int64) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return newBodyTooLargeError(limit)
+	}
+	return nil
+}
+
+func newBodyTooLargeError(limit int64) *Error {
+	return NewError(http.StatusRequestEntityTooLarge, "body_too_large",
+		fmt.Sprintf("Request body is larger than %d bytes", limit))
+}
+
+// positionedFieldsError returns a fields error with a body position,
+// i.e. `Bad request: Invalid body "age", must be a number, at line 2, column 10`.
+func positionedFieldsError(line int, column int, field FieldError) BadRequestError {
+	err := NewFieldsError(field)
+	err.Text += fmt.Sprintf(", at line %d, column %d", line, column)
+	return err
+}
+
+// jsonType returns a JSON type description of a Go type.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	}
+	return "a " + t.String()
+}
+
+// offsetReader records newline offsets of a read body to report line and column positions.
+type offsetReader struct {
+	r        io.Reader
+	offset   int64
+	newlines []int64
+}
+
+func (r *offsetReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			r.newlines = append(r.newlines, r.offset+int64(i))
+		}
+	}
+	r.offset += int64(n)
+	return n, err
+}
+
+// position returns a 1-based line and column of the last byte before an offset,
+// JSON errors occur after reading their offsets.
+func (r *offsetReader) position(offset int64) (line int, column int) {
+	if offset > 0 {
+		offset--
+	}
+
+	i := sort.Search(len(r.newlines), func(i int) bool { return r.newlines[i] >= offset })
+	start := int64(0)
+	if i > 0 {
+		start = r.newlines[i-1] + 1
+	}
+	return i + 1, int(offset-start) + 1
+}
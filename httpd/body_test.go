@@ -0,0 +1,133 @@
+package httpd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bodyUser struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestReq_DecodeJSON(t *testing.T) {
+	httpReq := newBodyRequest(http.MethodPost, "/", "application/json; charset=utf-8", `{"name": "John", "age": 30}`)
+
+	var user bodyUser
+	err := newReq(NewRouter(nil), httpReq, nil).DecodeJSON(&user)
+	assert.Nil(t, err)
+	assert.Equal(t, bodyUser{Name: "John", Age: 30}, user)
+}
+
+func TestReq_DecodeJSON__should_return_errors_with_positions(t *testing.T) {
+	decode := func(body string) error {
+		httpReq := newBodyRequest(http.MethodPost, "/", "application/json", body)
+		return newReq(NewRouter(nil), httpReq, nil).DecodeJSON(&bodyUser{})
+	}
+
+	assert.EqualError(t, decode("{\n  \"name\": x\n}"),
+		"Bad request: Invalid JSON at line 2, column 11, invalid character 'x' looking for beginning of value")
+	assert.EqualError(t, decode("{\"name\": \"John\",\n\"age\": \"30\"}"),
+		`Bad request: Invalid body "age", must be an integer, at line 2, column 11`)
+	assert.EqualError(t, decode(`[]`), "Bad request: Invalid JSON at line 1, column 1, expected an object")
+	assert.EqualError(t, decode(`{"name": "Jo`), "Bad request: Invalid JSON, unexpected end of body")
+	assert.EqualError(t, decode(``), "Bad request: Empty JSON body")
+
+	err := decode(`{"age": true}`)
+	assert.Equal(t, []FieldError{{Field: "age", In: "body", Code: "invalid", Message: "must be an integer"}},
+		err.(BadRequestError).Fields)
+}
+
+func TestReq_DecodeJSON__strict(t *testing.T) {
+	r := NewRouter(nil)
+	r.SetBodyPolicy(BodyPolicy{Strict: true})
+	decode := func(body string) error {
+		httpReq := newBodyRequest(http.MethodPost, "/", "application/json", body)
+		return newReq(r, httpReq, nil).DecodeJSON(&bodyUser{})
+	}
+
+	assert.Nil(t, decode(`{"name": "John"}`+"\n"))
+	assert.EqualError(t, decode(`{"name": "John", "admin": true}`),
+		`Bad request: Invalid body "admin", is not allowed, at line 1, column 31`)
+	assert.EqualError(t, decode(`{"name": "John"} {}`),
+		"Bad request: Invalid JSON at line 1, column 18, unexpected data after the value")
+	assert.EqualError(t, decode(`{"name": "John"} x`),
+		"Bad request: Invalid JSON at line 1, column 18, invalid character 'x' looking for beginning of value")
+}
+
+func TestReq_DecodeJSON__should_reject_content_types(t *testing.T) {
+	httpReq := newBodyRequest(http.MethodPost, "/", "text/plain", `{}`)
+	err := newReq(NewRouter(nil), httpReq, nil).DecodeJSON(&bodyUser{})
+	assert.Equal(t, http.StatusUnsupportedMediaType, err.(*Error).Status)
+
+	httpReq = newBodyRequest(http.MethodPost, "/", "application/json", `{}`)
+	httpReq.Header.Set("Content-Encoding", "br")
+	err = newReq(NewRouter(nil), httpReq, nil).DecodeJSON(&bodyUser{})
+	assert.EqualError(t, err, `Unsupported content encoding "br"`)
+}
+
+func TestReq_DecodeJSON__should_decompress_gzip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	gz.Write([]byte(`{"name": "John", "age": 30}`))
+	gz.Close()
+
+	httpReq := newBodyRequest(http.MethodPost, "/", "application/json", buf.String())
+	httpReq.Header.Set("Content-Encoding", "gzip")
+
+	var user bodyUser
+	err := newReq(NewRouter(nil), httpReq, nil).DecodeJSON(&user)
+	assert.Nil(t, err)
+	assert.Equal(t, bodyUser{Name: "John", Age: 30}, user)
+
+	httpReq = newBodyRequest(http.MethodPost, "/", "application/json", `{}`)
+	httpReq.Header.Set("Content-Encoding", "gzip")
+	err = newReq(NewRouter(nil), httpReq, nil).DecodeJSON(&user)
+	assert.EqualError(t, err, "Bad request: Invalid gzip body")
+}
+
+func TestReq_DecodeJSON__should_limit_body_size(t *testing.T) {
+	r := NewRouter(nil)
+	r.SetBodyPolicy(BodyPolicy{MaxSize: 32})
+	r.Middleware("/uploads", WithBodyPolicy(BodyPolicy{MaxSize: 1024}))
+
+	handler := func(ctx context.Context, req *Req, resp *Resp) error {
+		var user bodyUser
+		if err := req.DecodeJSON(&user); err != nil {
+			return err
+		}
+		return resp.Text(user.Name)
+	}
+	r.POST("/users", handler)
+	r.POST("/uploads", handler)
+
+	body := `{"name": "` + strings.Repeat("a", 64) + `"}`
+	w := serveRequest(r, newBodyRequest(http.MethodPost, "/users", "application/json", body))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "Request body is larger than 32 bytes")
+
+	// Unknown content length.
+	httpReq := newBodyRequest(http.MethodPost, "/users", "application/json", body)
+	httpReq.ContentLength = -1
+	w = serveRequest(r, httpReq)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// Decompressed size.
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	gz.Write([]byte(body))
+	gz.Close()
+	httpReq = newBodyRequest(http.MethodPost, "/users", "application/json", buf.String())
+	httpReq.Header.Set("Content-Encoding", "gzip")
+	w = serveRequest(r, httpReq)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = serveRequest(r, newBodyRequest(http.MethodPost, "/uploads", "application/json", body))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,30 @@
+package httpd
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// constraints are built-in param constraints, i.e. :id<int>.
+// All other constraints are treated as regular expressions, i.e. :slug<[a-z0-9-]+>.
+var constraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// compileConstraint returns an anchored regexp for a built-in constraint name or a regular expression.
+func compileConstraint(constraint string) *regexp.Regexp {
+	expr, ok := constraints[constraint]
+	if !ok {
+		expr = constraint
+	}
+
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic(fmt.Sprintf("router: Invalid param constraint %q, %v", constraint, err))
+	}
+	return re
+}
@@ -1,23 +1,160 @@
 package httpd
 
 import (
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 )
 
 var (
 	ErrRouteNotFound    = errors.New("router: Route not found")
 	ErrMethodNotAllowed = errors.New("router: Method not allowed")
+
+	ErrRouteNameNotFound = errors.New("router: Route name not found")
 )
 
 type BadRequestError struct {
-	Text string
+	Text   string
+	Fields []FieldError // Optional invalid fields, they are rendered as problem details.
 }
 
 func NewBadRequestError(text string) BadRequestError {
-	return BadRequestError{fmt.Sprintf("Bad request: %s", text)}
+	return BadRequestError{Text: fmt.Sprintf("Bad request: %s", text)}
+}
+
+// NewFieldsError returns a bad request error which lists invalid fields,
+// i.e. `Bad request: Invalid query "limit", must be an integer; Missing header "X-Tenant"`.
+func NewFieldsError(fields ...FieldError) BadRequestError {
+	texts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		texts = append(texts, f.text())
+	}
+	return BadRequestError{Text: "Bad request: " + strings.Join(texts, "; "), Fields: fields}
 }
 
 func (r BadRequestError) Error() string {
 	return r.Text
 }
+
+// Error is an HTTP error with a status, a machine-readable code, a public message, optional field details
+// and an internal cause. The router renders errors as application/problem+json (RFC 7807) and finds them
+// in wrapped errors, the cause is never rendered, it is logged in server errors.
+type Error struct {
+	Status  int
+	Code    string       // Machine-readable code, i.e. "not_found".
+	Message string       // Public message, i.e. "User not found".
+	Details []FieldError // Optional field details.
+	Cause   error        // Optional internal cause.
+}
+
+// FieldError describes an invalid request field.
+type FieldError struct {
+	Field   string `json:"field" xml:"field" yaml:"field"`
+	In      string `json:"in,omitempty" xml:"in,omitempty" yaml:"in,omitempty"`       // Optional field source, i.e. "query".
+	Code    string `json:"code,omitempty" xml:"code,omitempty" yaml:"code,omitempty"` // Machine-readable code, i.e. "required".
+	Message string `json:"message" xml:"message" yaml:"message"`
+}
+
+// text returns an error text, i.e. `Invalid query "limit", must be an integer` or `Missing header "X-Tenant"`.
+func (f FieldError) text() string {
+	name := strconv.Quote(f.Field)
+	if f.In != "" {
+		name = f.In + " " + name
+	}
+
+	if f.Code == "required" {
+		return "Missing " + name
+	}
+	return fmt.Sprintf("Invalid %v, %v", name, f.Message)
+}
+
+// NewError returns an error with a status, a code and a message, the message defaults to the status text.
+func NewError(status int, code string, message string) *Error {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func NewUnauthorizedError(message string) *Error {
+	return NewError(http.StatusUnauthorized, "unauthorized", message)
+}
+
+func NewForbiddenError(message string) *Error {
+	return NewError(http.StatusForbidden, "forbidden", message)
+}
+
+func NewNotFoundError(message string) *Error {
+	return NewError(http.StatusNotFound, "not_found", message)
+}
+
+func NewConflictError(message string) *Error {
+	return NewError(http.StatusConflict, "conflict", message)
+}
+
+func NewUnprocessableError(message string, details ...FieldError) *Error {
+	return NewError(http.StatusUnprocessableEntity, "unprocessable_entity", message).WithDetails(details...)
+}
+
+func NewTooManyRequestsError(message string) *Error {
+	return NewError(http.StatusTooManyRequests, "too_many_requests", message)
+}
+
+func NewServiceUnavailableError(message string) *Error {
+	return NewError(http.StatusServiceUnavailable, "service_unavailable", message)
+}
+
+func (e *Error) Error() string {
+	if e.Cause == nil {
+		return e.Message
+	}
+	return fmt.Sprintf("%v: %v", e.Message, e.Cause)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// WithCause returns a copy of the error with an internal cause.
+func (e *Error) WithCause(cause error) *Error {
+	e1 := *e
+	e1.Cause = cause
+	return &e1
+}
+
+// WithDetails returns a copy of the error with appended field details.
+func (e *Error) WithDetails(details ...FieldError) *Error {
+	e1 := *e
+	e1.Details = append(e.Details[:len(e.Details):len(e.Details)], details...)
+	return &e1
+}
+
+// problem is an RFC 7807 problem details object.
+type problem struct {
+	XMLName xml.Name     `json:"-" xml:"urn:ietf:rfc:7807 problem" yaml:"-"`
+	Type    string       `json:"type" xml:"type" yaml:"type"`
+	Title   string       `json:"title" xml:"title" yaml:"title"`
+	Status  int          `json:"status" xml:"status" yaml:"status"`
+	Detail  string       `json:"detail,omitempty" xml:"detail,omitempty" yaml:"detail,omitempty"`
+	Code    string       `json:"code,omitempty" xml:"code,omitempty" yaml:"code,omitempty"`
+	Errors  []FieldError `json:"errors,omitempty" xml:"errors>error,omitempty" yaml:"errors,omitempty"`
+}
+
+func newProblem(e *Error) problem {
+	return problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(e.Status),
+		Status: e.Status,
+		Detail: e.Message,
+		Code:   e.Code,
+		Errors: e.Details,
+	}
+}
+
+// String returns the problem detail, it is used in text responses.
+func (p problem) String() string {
+	return p.Detail
+}
@@ -0,0 +1,49 @@
+package httpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError__should_unwrap_cause(t *testing.T) {
+	cause := errors.New("user 1 is missing")
+	err := fmt.Errorf("load user: %w", NewNotFoundError("User not found").WithCause(cause))
+
+	var httpErr *Error
+	assert.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.Status)
+	assert.Equal(t, "not_found", httpErr.Code)
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, "load user: User not found: user 1 is missing", err.Error())
+}
+
+func TestNewError__should_default_message_to_status_text(t *testing.T) {
+	err := NewTooManyRequestsError("")
+	assert.Equal(t, http.StatusTooManyRequests, err.Status)
+	assert.Equal(t, "Too Many Requests", err.Message)
+}
+
+func TestRouter_ServeHTTP__should_render_errors_as_problems(t *testing.T) {
+	r := NewRouter(nil)
+	r.POST("/users", func(ctx context.Context, req *Req, resp *Resp) error {
+		err := NewUnprocessableError("Invalid user", FieldError{Field: "email", Code: "email", Message: "Invalid email"})
+		return fmt.Errorf("create user: %w", err.WithCause(errors.New("internal")))
+	})
+
+	w := serve(r, http.MethodPost, "/users")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Unprocessable Entity",
+		"status": 422,
+		"detail": "Invalid user",
+		"code": "unprocessable_entity",
+		"errors": [{"field": "email", "code": "email", "message": "Invalid email"}]
+	}`, w.Body.String())
+}
@@ -0,0 +1,115 @@
+package httpd
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group registers routes under a common prefix with middleware which applies only to the group routes,
+// not to other handlers which share the same route nodes. The middleware also applies to OPTIONS
+// requests to the group routes including automatic OPTIONS responses, i.e. CORS preflight requests.
+type Group struct {
+	router  *Router // Optional router, panics on registrations when frozen.
+	route   *Route
+	prefix  string
+	middle  []Middleware
+	options map[*Route]bool // Routes with the group middleware applied to OPTIONS.
+}
+
+// Group returns a group with a prefix and middleware, i.e.
+//
+//	api := router.Group("/api", auth)
+//	api.GET("/users", listUsers)
+func (r *Route) Group(prefix string, middleware ...Middleware) *Group {
+	g := &Group{route: r}
+	return g.Group(prefix, middleware...)
+}
+
+// Group returns a nested group, its middleware runs after the parent group middleware.
+func (g *Group) Group(prefix string, middleware ...Middleware) *Group {
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		panic("router: Group prefix must start with a slash")
+	}
+	for _, m := range middleware {
+		if m == nil {
+			panic("router: Nil middleware")
+		}
+	}
+
+	middle := make([]Middleware, 0, len(g.middle)+len(middleware))
+	middle = append(middle, g.middle...)
+	middle = append(middle, middleware...)
+
+	return &Group{
+		router:  g.router,
+		route:   g.route,
+		prefix:  g.prefix + strings.TrimSuffix(prefix, "/"),
+		middle:  middle,
+		options: make(map[*Route]bool),
+	}
+}
+
+func (g *Group) ALL(pattern string, h Handler) *Route     { return g.Handler(ALL, pattern, h) }
+func (g *Group) HEAD(pattern string, h Handler) *Route    { return g.Handler(HEAD, pattern, h) }
+func (g *Group) GET(pattern string, h Handler) *Route     { return g.Handler(GET, pattern, h) }
+func (g *Group) POST(pattern string, h Handler) *Route    { return g.Handler(POST, pattern, h) }
+func (g *Group) PUT(pattern string, h Handler) *Route     { return g.Handler(PUT, pattern, h) }
+func (g *Group) PATCH(pattern string, h Handler) *Route   { return g.Handler(PATCH, pattern, h) }
+func (g *Group) DELETE(pattern string, h Handler) *Route  { return g.Handler(DELETE, pattern, h) }
+func (g *Group) OPTIONS(pattern string, h Handler) *Route { return g.Handler(OPTIONS, pattern, h) }
+func (g *Group) TRACE(pattern string, h Handler) *Route   { return g.Handler(TRACE, pattern, h) }
+func (g *Group) CONNECT(pattern string, h Handler) *Route { return g.Handler(CONNECT, pattern, h) }
+
+func (g *Group) Static(pattern string, dir http.Dir) {
+	if !strings.HasSuffix(pattern, "/*") {
+		panic("router: Static path must end with /*")
+	}
+
+	g.GET(pattern, NewStaticHandler(dir))
+}
+
+// Handler adds a method handler with the group middleware and returns the pattern route.
+func (g *Group) Handler(method string, pattern string, h Handler) *Route {
+	if pattern != "" && !strings.HasPrefix(pattern, "/") {
+		panic("router: Pattern must start with a slash")
+	}
+
+	route := g.root().Handler(method, g.prefix+pattern, h)
+	if len(g.middle) == 0 {
+		return route
+	}
+
+	method = strings.ToUpper(method)
+	if method != OPTIONS {
+		route.addMethodMiddleware(method, g.middle...)
+	}
+	if method != ALL && !g.options[route] {
+		route.addMethodMiddleware(OPTIONS, g.middle...)
+		g.options[route] = true
+	}
+	return route
+}
+
+// root returns the group root route, panics when the group router is frozen.
+func (g *Group) root() *Route {
+	if g.router != nil {
+		g.router.load().checkFrozen()
+	}
+	return g.route
+}
+
+// Methods adds a handler for multiple methods with the group middleware and returns the pattern route.
+func (g *Group) Methods(methods []string, pattern string, h Handler) *Route {
+	if len(methods) == 0 {
+		panic("router: No methods")
+	}
+
+	var route *Route
+	for _, method := range methods {
+		if method == ALL {
+			panic("router: ALL method in a method list")
+		}
+		route = g.Handler(method, pattern, h)
+	}
+	return route
+}
@@ -0,0 +1,81 @@
+package httpd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup__should_apply_middleware_only_to_group_routes(t *testing.T) {
+	r := NewRouter(nil)
+	api := r.Group("/api", headerMiddleware("X-Api"))
+	api.GET("/users", textHandler("users"))
+	api.Group("/admin", headerMiddleware("X-Admin")).GET("/stats", textHandler("stats"))
+	r.POST("/api/users", textHandler("create"))
+
+	w := serve(r, http.MethodGet, "/api/users")
+	assert.Equal(t, "users", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("X-Api"))
+
+	w = serve(r, http.MethodPost, "/api/users")
+	assert.Equal(t, "create", w.Body.String())
+	assert.Equal(t, "", w.Header().Get("X-Api"))
+
+	w = serve(r, http.MethodGet, "/api/admin/stats")
+	assert.Equal(t, "stats", w.Body.String())
+	assert.Equal(t, "true", w.Header().Get("X-Api"))
+	assert.Equal(t, "true", w.Header().Get("X-Admin"))
+
+	w = serve(r, http.MethodHead, "/api/users")
+	assert.Equal(t, "true", w.Header().Get("X-Api"))
+}
+
+func TestGroup__should_apply_middleware_to_automatic_options(t *testing.T) {
+	r := NewRouter(nil)
+	api := r.Group("/api", headerMiddleware("X-Cors"))
+	api.GET("/users", textHandler("users"))
+	api.POST("/users", textHandler("create"))
+	r.GET("/public", textHandler("public"))
+
+	for _, freeze := range []bool{false, true} {
+		if freeze {
+			r.Freeze()
+		}
+
+		w := serve(r, http.MethodOptions, "/api/users")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "true", w.Header().Get("X-Cors"))
+		assert.Equal(t, "GET, HEAD, OPTIONS, POST", w.Header().Get("Allow"))
+
+		w = serve(r, http.MethodOptions, "/public")
+		assert.Equal(t, "", w.Header().Get("X-Cors"))
+	}
+
+	chain, err := r.Chain(httptest.NewRequest(http.MethodOptions, "/api/users", nil))
+	assert.Nil(t, err)
+	assert.Len(t, chain, 2)
+}
+
+func TestGroup__should_panic_when_router_is_frozen(t *testing.T) {
+	r := NewRouter(nil)
+	api := r.Group("/api")
+	api.GET("/users", dummyHandler)
+	r.Freeze()
+
+	assert.PanicsWithValue(t, "router: Router is frozen", func() {
+		api.GET("/posts", dummyHandler)
+	})
+	assert.PanicsWithValue(t, "router: Router is frozen", func() {
+		api.Group("/admin").GET("/stats", dummyHandler)
+	})
+}
+
+func headerMiddleware(header string) Middleware {
+	return func(ctx context.Context, req *Req, resp *Resp, next Handler) error {
+		resp.Header().Set(header, "true")
+		return next(ctx, req, resp)
+	}
+}
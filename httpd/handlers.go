@@ -0,0 +1,158 @@
+package httpd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrorHandler handles an error returned by middleware or a handler.
+type ErrorHandler func(ctx context.Context, req *Req, resp *Resp, err error)
+
+// PanicHandler handles a panic recovered from middleware or a handler. The response can be
+// already started, i.e. when resp.Status is not zero, the handler can re-panic http.ErrAbortHandler
+// to abort it.
+type PanicHandler func(ctx context.Context, req *Req, resp *Resp, p *Panic)
+
+// ErrorHandlers handle not found routes, not allowed methods, errors and panics.
+//
+// Nil handlers fall back to the handlers of the parent routes, then to the router handlers
+// and then to the default ones. Not found and method not allowed handlers are executed
+// with the subtree middleware of the longest matching pattern prefix, i.e. the /api middleware
+// is executed for /api/unknown. The Allow header is set before method not allowed handlers.
+type ErrorHandlers struct {
+	NotFound         Handler
+	MethodNotAllowed Handler
+	Error            ErrorHandler
+	Panic            PanicHandler
+}
+
+var defaultErrorHandlers = ErrorHandlers{
+	NotFound:         notFound,
+	MethodNotAllowed: methodNotAllowed,
+	Error:            handleError,
+	Panic:            handlePanic,
+}
+
+// SetErrorHandlers sets the router error handlers, they apply to all host trees.
+func (r *Router) SetErrorHandlers(handlers ErrorHandlers) {
+	r.load().checkFrozen()
+	r.errors = handlers
+}
+
+// ErrorHandlers sets error handlers for a pattern subtree, i.e. JSON errors for /api,
+// replaces only the non-nil handlers.
+func (r *Route) ErrorHandlers(pattern string, handlers ErrorHandlers) {
+	route := r.makePath(pattern)
+	if route.Errors == nil {
+		route.Errors = &ErrorHandlers{}
+	}
+	route.Errors.override(handlers)
+}
+
+func (h *ErrorHandlers) override(h1 ErrorHandlers) {
+	if h1.NotFound != nil {
+		h.NotFound = h1.NotFound
+	}
+	if h1.MethodNotAllowed != nil {
+		h.MethodNotAllowed = h1.MethodNotAllowed
+	}
+	if h1.Error != nil {
+		h.Error = h1.Error
+	}
+	if h1.Panic != nil {
+		h.Panic = h1.Panic
+	}
+}
+
+// errorHandlers returns the error handlers and the subtree middleware of the longest
+// path prefix which resolves in a route tree, or the router handlers when the tree is nil.
+func (r *Router) errorHandlers(route *Route, path string) (ErrorHandlers, []Middleware) {
+	handlers := defaultErrorHandlers
+	handlers.override(r.errors)
+	if route == nil {
+		return handlers, nil
+	}
+
+	var middleware []Middleware
+	for _, route := range scope(route, path, r.pathOptions()) {
+		if route.Errors != nil {
+			handlers.override(*route.Errors)
+		}
+		middleware = append(middleware, route.Middle...)
+	}
+	return handlers, middleware
+}
+
+// scope returns the routes of the longest path prefix which resolves in a route tree.
+func scope(route *Route, path string, opts matchOptions) []*Route {
+	for {
+		routes, _, err := route.resolvePath(path, opts)
+		if err == nil {
+			return routes
+		}
+
+		i := strings.LastIndex(path, "/")
+		if i <= 0 {
+			return []*Route{route}
+		}
+		path = path[:i]
+	}
+}
+
+func notFound(ctx context.Context, req *Req, resp *Resp) error {
+	return resp.Problem(NewNotFoundError(""))
+}
+
+func methodNotAllowed(ctx context.Context, req *Req, resp *Resp) error {
+	return resp.Problem(NewError(http.StatusMethodNotAllowed, "method_not_allowed", ""))
+}
+
+// handleError renders errors as problems, finds Error and BadRequestError in wrapped errors, logs server errors.
+func handleError(ctx context.Context, req *Req, resp *Resp, err error) {
+	var httpErr *Error
+	var bad BadRequestError
+
+	switch {
+	case errors.Is(err, ErrRouteNotFound):
+		notFound(ctx, req, resp)
+
+	case errors.Is(err, ErrMethodNotAllowed):
+		methodNotAllowed(ctx, req, resp)
+
+	case errors.As(err, &httpErr):
+		resp.Problem(httpErr)
+		if httpErr.Status >= 500 || httpErr.Status == 0 {
+			logError(ctx, req, err)
+		}
+
+	case errors.As(err, &bad):
+		resp.Problem(NewError(http.StatusBadRequest, "bad_request", bad.Text).WithDetails(bad.Fields...))
+
+	default:
+		resp.Problem(newInternalError())
+		logError(ctx, req, err)
+	}
+}
+
+func logError(ctx context.Context, req *Req, err error) {
+	if req.Router.log != nil {
+		req.Router.log.Error(ctx, "Internal server error", err)
+	}
+}
+
+// handlePanic logs a panic with the request dump, aborts the response when it is already started.
+func handlePanic(ctx context.Context, req *Req, resp *Resp, p *Panic) {
+	if req.Router.log != nil {
+		req.Router.log.Error(ctx, p)
+	}
+	if resp.Status != 0 {
+		panic(http.ErrAbortHandler)
+	}
+	resp.Problem(newInternalError())
+}
+
+func newInternalError() *Error {
+	return NewError(http.StatusInternalServerError, "internal_server_error", "Internal server error")
+}
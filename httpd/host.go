@@ -0,0 +1,193 @@
+package httpd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// hostPattern is a host route tree with param or wildcard labels,
+// i.e. ":tenant.example.com" or "*.example.com".
+type hostPattern struct {
+	pattern string
+	labels  []string
+	port    string
+	route   *Route
+}
+
+// Host returns a route tree for a host pattern, creates the tree when absent.
+//
+// A pattern consists of dot-separated labels and an optional port, i.e. "api.example.com",
+// "example.com:8080", ":tenant.example.com" or "*.example.com". A param label matches one label
+// and adds its value to the request params, a wildcard matches one or more leading labels.
+// A pattern without a port matches any port. Static hosts are matched first, then patterns
+// in the order they were added, requests with unmatched hosts are served by the default tree.
+func (r *Router) Host(pattern string) *Route {
+	t := r.load()
+	t.checkFrozen()
+
+	h := parseHostPattern(pattern)
+	if h == nil {
+		pattern = strings.ToLower(pattern)
+		route, ok := t.hosts[pattern]
+		if !ok {
+			route = NewRoute()
+			t.hosts[pattern] = route
+		}
+		return route
+	}
+
+	for _, h0 := range t.hostPatterns {
+		if h0.pattern == h.pattern {
+			return h0.route
+		}
+	}
+
+	h.route = NewRoute()
+	t.hostPatterns = append(t.hostPatterns, h)
+	return h.route
+}
+
+// parseHostPattern validates a host pattern and returns a host pattern without a route,
+// or nil when the pattern is static.
+// Static labels are lowercased, param names are kept as written.
+func parseHostPattern(pattern string) *hostPattern {
+	name, port := splitHostPort(pattern)
+	if name == "" {
+		panic("router: Empty host pattern")
+	}
+
+	labels := strings.Split(name, ".")
+	static := true
+	for i, label := range labels {
+		switch {
+		case label == "":
+			panic(fmt.Sprintf("router: Empty label in host pattern %q", pattern))
+		case label == catchAllSegment:
+			if i != 0 {
+				panic(fmt.Sprintf("router: Wildcard must be the first label in host pattern %q", pattern))
+			}
+			static = false
+		case strings.HasPrefix(label, paramSegment):
+			if label == paramSegment {
+				panic(fmt.Sprintf("router: Empty param name in host pattern %q", pattern))
+			}
+			if !isParamName(label[1:]) {
+				panic(fmt.Sprintf("router: Invalid param name in host pattern %q", pattern))
+			}
+			static = false
+		default:
+			labels[i] = strings.ToLower(label)
+		}
+	}
+
+	if static {
+		return nil
+	}
+
+	pattern = strings.Join(labels, ".")
+	if port != "" {
+		pattern += ":" + port
+	}
+	return &hostPattern{
+		pattern: pattern,
+		labels:  labels,
+		port:    port,
+	}
+}
+
+// matchHost returns a route tree for a request host and host params, or the default tree.
+func (t *table) matchHost(host string) (*Route, Params) {
+	if len(t.hosts) == 0 && len(t.hostPatterns) == 0 {
+		return t.route, nil
+	}
+
+	host = strings.ToLower(host)
+	name, port := splitHostPort(host)
+	name = strings.TrimSuffix(name, ".")
+
+	if port != "" {
+		if route, ok := t.hosts[name+":"+port]; ok {
+			return route, nil
+		}
+	}
+	if route, ok := t.hosts[name]; ok {
+		return route, nil
+	}
+
+	labels := strings.Split(name, ".")
+	for _, h := range t.hostPatterns {
+		if params, ok := h.match(labels, port); ok {
+			return h.route, params
+		}
+	}
+	return t.route, nil
+}
+
+func (h *hostPattern) match(labels []string, port string) (Params, bool) {
+	if h.port != "" && h.port != port {
+		return nil, false
+	}
+
+	patterns := h.labels
+	if patterns[0] == catchAllSegment {
+		patterns = patterns[1:]
+		if len(labels) <= len(patterns) {
+			return nil, false
+		}
+		labels = labels[len(labels)-len(patterns):]
+	}
+	if len(labels) != len(patterns) {
+		return nil, false
+	}
+
+	var params Params
+	for i, pattern := range patterns {
+		label := labels[i]
+
+		switch {
+		case strings.HasPrefix(pattern, paramSegment):
+			if label == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = Params{}
+			}
+			params[pattern[1:]] = label
+
+		case pattern != label:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// splitHostPort splits a host into a name and an optional numeric port,
+// unlike net.SplitHostPort it accepts hosts without ports and param labels.
+func splitHostPort(host string) (name string, port string) {
+	if strings.HasPrefix(host, "[") {
+		if name, port, err := net.SplitHostPort(host); err == nil {
+			return name, port
+		}
+		return strings.TrimSuffix(host[1:], "]"), ""
+	}
+
+	i := strings.LastIndex(host, ":")
+	if i <= 0 || !isDigits(host[i+1:]) {
+		return host, ""
+	}
+	return host[:i], host[i+1:]
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
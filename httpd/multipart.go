@@ -0,0 +1,277 @@
+package httpd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// sniffLen is the number of bytes used to detect file content types, see http.DetectContentType.
+const sniffLen = 512
+
+// DefaultMaxFieldSize is the default max size of multipart non-file fields, see MultipartPolicy.
+const DefaultMaxFieldSize = 1 << 20
+
+// MultipartPolicy limits multipart uploads, see Req.Multipart.
+type MultipartPolicy struct {
+	MaxFileSize  int64    // Max size of a file, zero is unlimited.
+	MaxFieldSize int64    // Max size of a non-file field, zero is DefaultMaxFieldSize, negative is unlimited.
+	MaxTotalSize int64    // Max size of the whole body, zero is the default, negative is unlimited, see Req.Multipart.
+	MaxFiles     int      // Max number of files, zero is unlimited.
+	AllowedTypes []string // Allowed sniffed file content types, i.e. "image/png" or "image/*", empty allows all.
+	TempDir      string   // Directory of spooled files, empty is os.TempDir, see Part.Spool.
+}
+
+// MultipartReader iterates multipart/form-data parts as streams.
+type MultipartReader struct {
+	req    *Req
+	policy MultipartPolicy
+	reader *multipart.Reader
+	limit  int64 // Max body size, zero is unlimited.
+	files  int
+}
+
+// Part is a multipart field or file. It reads the part data, its size and hash are known after
+// the data is read, files are limited and their content types are sniffed, see MultipartPolicy.
+type Part struct {
+	Name        string // Form field name.
+	Filename    string // File name without a directory, empty in fields.
+	ContentType string // Sniffed content type of files, declared content type of fields.
+	Size        int64  // Size of the read data.
+	Hash        string // Hex SHA-256 hash of the data, set when the data is read to the end.
+	Path        string // Spooled file path, see Spool.
+	Header      textproto.MIMEHeader
+
+	mr    *MultipartReader
+	r     io.Reader
+	limit int64
+	hash  hash.Hash
+}
+
+// Multipart returns a multipart/form-data reader, i.e.
+//
+//	parts, err := req.Multipart(httpd.MultipartPolicy{
+//		MaxFileSize:  10 << 20,
+//		MaxFiles:     5,
+//		AllowedTypes: []string{"image/*", "application/pdf"},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	for {
+//		part, err := parts.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		if part.Filename == "" {
+//			value, err := part.Value()
+//			...
+//		}
+//		path, err := part.Spool()
+//		...
+//	}
+//
+// The default max total size is the body policy max size for fields plus MaxFileSize for each
+// of MaxFiles files, or for one file when MaxFiles is zero. Limit and content type errors are returned
+// as 413 and 415 Errors from the reader and the parts.
+func (r *Req) Multipart(policy MultipartPolicy) (*MultipartReader, error) {
+	contentType := r.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return nil, NewError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			fmt.Sprintf("Unsupported content type %q, expected multipart/form-data", contentType))
+	}
+
+	var body io.Reader = r.Body
+	if r.Body == nil {
+		body = http.NoBody
+	}
+
+	limit := policy.maxTotalSize(r.bodyPolicy.maxSize())
+	if limit > 0 {
+		if r.ContentLength > limit {
+			return nil, newBodyTooLargeError(limit)
+		}
+		body = http.MaxBytesReader(nil, io.NopCloser(body), limit)
+	}
+
+	return &MultipartReader{
+		req:    r,
+		policy: policy,
+		reader: multipart.NewReader(body, params["boundary"]),
+		limit:  limit,
+	}, nil
+}
+
+// Next returns the next part or io.EOF, it skips the unread data of the previous part.
+func (m *MultipartReader) Next() (*Part, error) {
+	p, err := m.reader.NextPart()
+	if err != nil {
+		return nil, m.error(err)
+	}
+
+	part := &Part{
+		Name:        p.FormName(),
+		Filename:    p.FileName(),
+		ContentType: p.Header.Get("Content-Type"),
+		Header:      p.Header,
+		mr:          m,
+		r:           p,
+		hash:        sha256.New(),
+	}
+	if part.Filename == "" {
+		if limit := m.policy.maxFieldSize(); limit > 0 {
+			part.limit = limit
+			part.r = io.LimitReader(p, limit+1)
+		}
+		return part, nil
+	}
+
+	m.files++
+	if m.policy.MaxFiles > 0 && m.files > m.policy.MaxFiles {
+		return nil, NewError(http.StatusRequestEntityTooLarge, "too_many_files",
+			fmt.Sprintf("Too many files, max %d", m.policy.MaxFiles))
+	}
+
+	if m.policy.MaxFileSize > 0 {
+		part.limit = m.policy.MaxFileSize
+		part.r = io.LimitReader(p, part.limit+1)
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(part.r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, m.error(err)
+	}
+	head = head[:n]
+	part.r = io.MultiReader(bytes.NewReader(head), part.r)
+	part.ContentType = http.DetectContentType(head)
+
+	if !allowedType(m.policy.AllowedTypes, part.ContentType) {
+		return nil, NewError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+			fmt.Sprintf("File %q has an unsupported content type %q", part.Filename, part.ContentType))
+	}
+	return part, nil
+}
+
+func (m *MultipartReader) error(err error) error {
+	if err == io.EOF {
+		return err
+	}
+	if bodyErr := bodyError(err, m.limit); bodyErr != nil {
+		return bodyErr
+	}
+	return NewBadRequestError(fmt.Sprintf("Invalid multipart body, %v", err))
+}
+
+// Read reads the part data, returns an error when a file or a field exceeds its max size.
+func (p *Part) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if p.limit > 0 && p.Size+int64(n) > p.limit {
+		if p.Filename == "" {
+			return 0, NewError(http.StatusRequestEntityTooLarge, "field_too_large",
+				fmt.Sprintf("Field %q is larger than %d bytes", p.Name, p.limit))
+		}
+		return 0, NewError(http.StatusRequestEntityTooLarge, "file_too_large",
+			fmt.Sprintf("File %q is larger than %d bytes", p.Filename, p.limit))
+	}
+
+	p.Size += int64(n)
+	p.hash.Write(b[:n])
+	switch {
+	case err == io.EOF:
+		p.Hash = hex.EncodeToString(p.hash.Sum(nil))
+	case err != nil:
+		err = p.mr.error(err)
+	}
+	return n, err
+}
+
+// Value reads a field value, it is limited by the max field size, see MultipartPolicy.
+func (p *Part) Value() (string, error) {
+	b, err := io.ReadAll(p)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Spool copies the unread part data to a temp file and returns its path. The file is removed
+// when the handler returns.
+func (p *Part) Spool() (string, error) {
+	f, err := os.CreateTemp(p.mr.policy.TempDir, "upload-*")
+	if err != nil {
+		return "", err
+	}
+	p.mr.req.onDone(func() { os.Remove(f.Name()) })
+
+	_, err = io.Copy(f, p)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	p.Path = f.Name()
+	return p.Path, nil
+}
+
+func (p MultipartPolicy) maxFieldSize() int64 {
+	switch {
+	case p.MaxFieldSize == 0:
+		return DefaultMaxFieldSize
+	case p.MaxFieldSize < 0:
+		return 0
+	}
+	return p.MaxFieldSize
+}
+
+// maxTotalSize returns a max body size, zero is unlimited.
+func (p MultipartPolicy) maxTotalSize(bodySize int64) int64 {
+	switch {
+	case p.MaxTotalSize < 0:
+		return 0
+	case p.MaxTotalSize > 0:
+		return p.MaxTotalSize
+	case p.MaxFileSize <= 0:
+		return bodySize
+	}
+
+	files := int64(p.MaxFiles)
+	if files <= 0 {
+		files = 1
+	}
+	if p.MaxFileSize > (math.MaxInt64-bodySize)/files {
+		return 0
+	}
+	return bodySize + p.MaxFileSize*files
+}
+
+// allowedType returns true when a content type matches an allow-list, i.e. "image/png" or "image/*".
+func allowedType(allowed []string, contentType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, a := range allowed {
+		if a == mediaType || strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, a[:len(a)-1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package httpd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testPNG = "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 64)
+
+func TestReq_Multipart(t *testing.T) {
+	var path string
+	r := NewRouter(nil)
+	r.POST("/upload", func(ctx context.Context, req *Req, resp *Resp) error {
+		parts, err := req.Multipart(MultipartPolicy{
+			MaxFileSize:  1024,
+			AllowedTypes: []string{"image/*"},
+			TempDir:      t.TempDir(),
+		})
+		if err != nil {
+			return err
+		}
+
+		part, err := parts.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, "title", part.Name)
+		value, err := part.Value()
+		assert.Nil(t, err)
+		assert.Equal(t, "Photo", value)
+
+		part, err = parts.Next()
+		assert.Nil(t, err)
+		assert.Equal(t, "file", part.Name)
+		assert.Equal(t, "photo.png", part.Filename)
+		assert.Equal(t, "image/png", part.ContentType)
+
+		path, err = part.Spool()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(len(testPNG)), part.Size)
+		assert.Equal(t, sha256Hex(testPNG), part.Hash)
+
+		data, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, testPNG, string(data))
+
+		_, err = parts.Next()
+		assert.Equal(t, io.EOF, err)
+		return resp.Text("OK")
+	})
+
+	w := serveRequest(r, newMultipartRequest("/upload", "title", "Photo", "file:photo.png", testPNG))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	_, err := os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReq_Multipart__should_enforce_limits(t *testing.T) {
+	upload := func(policy MultipartPolicy, pairs ...string) error {
+		req := newReq(NewRouter(nil), newMultipartRequest("/", pairs...), nil)
+		defer req.cleanup()
+
+		parts, err := req.Multipart(policy)
+		if err != nil {
+			return err
+		}
+		for {
+			part, err := parts.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if _, err := part.Spool(); err != nil {
+				return err
+			}
+		}
+	}
+
+	err := upload(MultipartPolicy{MaxFileSize: 16}, "file:a.png", testPNG)
+	assert.EqualError(t, err, `File "a.png" is larger than 16 bytes`)
+
+	err = upload(MultipartPolicy{MaxFiles: 1}, "file:a.png", testPNG, "file:b.png", testPNG)
+	assert.EqualError(t, err, "Too many files, max 1")
+
+	err = upload(MultipartPolicy{MaxTotalSize: 128}, "file:a.png", strings.Repeat("a", 256))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*Error).Status)
+
+	err = upload(MultipartPolicy{MaxFieldSize: 4}, "title", "Photo")
+	assert.EqualError(t, err, `Field "title" is larger than 4 bytes`)
+
+	err = upload(MultipartPolicy{MaxTotalSize: -1}, "title", strings.Repeat("a", DefaultMaxFieldSize+1))
+	assert.Equal(t, "field_too_large", err.(*Error).Code)
+
+	err = upload(MultipartPolicy{MaxFieldSize: -1, MaxTotalSize: -1}, "title", strings.Repeat("a", DefaultMaxFieldSize+1))
+	assert.Nil(t, err)
+
+	err = upload(MultipartPolicy{AllowedTypes: []string{"image/png"}}, "file:a.exe", "MZ\x90\x00")
+	assert.EqualError(t, err, `File "a.exe" has an unsupported content type "application/octet-stream"`)
+
+	err = upload(MultipartPolicy{AllowedTypes: []string{"text/plain"}}, "file:a.txt", "hello")
+	assert.Nil(t, err)
+
+	// The body policy limits the total size by default.
+	r := NewRouter(nil)
+	r.SetBodyPolicy(BodyPolicy{MaxSize: 128})
+	req := newReq(r, newMultipartRequest("/", "file:a.png", strings.Repeat("a", 256)), nil)
+	_, err = req.Multipart(MultipartPolicy{})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*Error).Status)
+
+	// Files up to MaxFileSize are allowed by default.
+	err = upload(MultipartPolicy{MaxFileSize: 10 << 20, MaxFiles: 5}, "file:a.txt", strings.Repeat("a", 2<<20))
+	assert.Nil(t, err)
+
+	err = upload(MultipartPolicy{MaxFileSize: 2 << 20}, "file:a.txt", strings.Repeat("a", 2<<20),
+		"file:b.txt", strings.Repeat("a", 2<<20))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*Error).Status)
+
+	req = newReq(NewRouter(nil), newBodyRequest(http.MethodPost, "/", "application/json", "{}"), nil)
+	_, err = req.Multipart(MultipartPolicy{})
+	assert.Equal(t, http.StatusUnsupportedMediaType, err.(*Error).Status)
+}
+
+// newMultipartRequest returns a multipart request from key-value pairs, "file:<name>" keys are files.
+func newMultipartRequest(target string, pairs ...string) *http.Request {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	for i := 0; i < len(pairs); i += 2 {
+		key, value := pairs[i], pairs[i+1]
+		if filename, ok := strings.CutPrefix(key, "file:"); ok {
+			fw, _ := w.CreateFormFile("file", filename)
+			fw.Write([]byte(value))
+		} else {
+			w.WriteField(key, value)
+		}
+	}
+	w.Close()
+	return newBodyRequest(http.MethodPost, target, w.FormDataContentType(), body.String())
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
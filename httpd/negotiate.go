@@ -0,0 +1,243 @@
+package httpd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Encoder encodes a value, i.e. into JSON or XML.
+type Encoder func(w io.Writer, v interface{}) error
+
+type encoder struct {
+	mediaType   string
+	contentType string
+	encode      Encoder
+}
+
+var textEncoder = encoder{"text/plain", "text/plain; charset=utf-8", encodeText}
+
+// defaultEncoders returns the built-in encoders in the order of the server preference.
+func defaultEncoders() []encoder {
+	return []encoder{
+		{"application/json", "application/json; charset=utf-8", encodeJSON},
+		{"application/xml", "application/xml; charset=utf-8", encodeXML},
+		{"application/yaml", "application/yaml; charset=utf-8", encodeYAML},
+		textEncoder,
+	}
+}
+
+// RegisterEncoder adds an encoder for a media type or replaces the current one, i.e. "application/msgpack".
+// Encoders are preferred in the order they were added when the client accepts multiple media types
+// with the same quality, the built-in JSON, XML, YAML and text encoders are added first.
+func (r *Router) RegisterEncoder(mediaType string, enc Encoder) {
+	r.load().checkFrozen()
+	if enc == nil {
+		panic("router: Nil encoder")
+	}
+
+	mediaType = strings.ToLower(mediaType)
+	if i := strings.Index(mediaType, "/"); i <= 0 || i == len(mediaType)-1 || strings.ContainsAny(mediaType, "*;, ") {
+		panic(fmt.Sprintf("router: Invalid media type %q", mediaType))
+	}
+
+	e := encoder{mediaType, mediaType, enc}
+	for i, e0 := range r.encoders {
+		if e0.mediaType == mediaType {
+			r.encoders[i] = e
+			return
+		}
+	}
+	r.encoders = append(r.encoders, e)
+}
+
+// Negotiate serves an OK response with an encoder selected by the Accept header,
+// returns a 406 Error when no encoder is acceptable, see RegisterEncoder.
+func (r *Resp) Negotiate(v interface{}) error {
+	return r.NegotiateStatus(v, http.StatusOK)
+}
+
+// NegotiateStatus serves a response with an encoder selected by the Accept header, see Negotiate.
+func (r *Resp) NegotiateStatus(v interface{}, status int) error {
+	r.Header().Add("Vary", "Accept")
+
+	enc, ok := negotiate(r.Router.encoders, r.accept, false)
+	if !ok {
+		mediaTypes := make([]string, 0, len(r.Router.encoders))
+		for _, e := range r.Router.encoders {
+			mediaTypes = append(mediaTypes, e.mediaType)
+		}
+		return NewError(http.StatusNotAcceptable, "not_acceptable",
+			"Acceptable media types: "+strings.Join(mediaTypes, ", "))
+	}
+	return r.encode(enc, enc.contentType, v, status)
+}
+
+// encode serves a value encoded into a buffer, returns an error when no response is written yet.
+func (r *Resp) encode(enc encoder, contentType string, v interface{}, status int) error {
+	buf := r.Router.getBuffer()
+	defer r.Router.releaseBuffer(buf)
+	if err := enc.encode(buf, v); err != nil {
+		return err
+	}
+
+	r.SetContentType(contentType)
+	r.SetContentLength(int64(buf.Len()))
+	r.WriteHeader(status)
+	r.Write(buf.Bytes())
+	return nil
+}
+
+// negotiate returns the encoder with the highest quality in the Accept header or the first one
+// when the header is empty. Problem encoders also match problem media types, i.e. "application/problem+json".
+func negotiate(encoders []encoder, accept string, problem bool) (encoder, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return encoders[0], true
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := encoder{}, 0.0
+	for _, e := range encoders {
+		q := quality(ranges, e.mediaType)
+		if problem {
+			if q1 := quality(ranges, problemMediaType(e.mediaType)); q1 > q {
+				q = q1
+			}
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// mediaRange is a media range in an Accept header, i.e. "text/*;q=0.5".
+type mediaRange struct {
+	mediaType string
+	subtype   string
+	q         float64
+}
+
+// parseAccept parses an Accept header, skips invalid media ranges.
+func parseAccept(accept string) []mediaRange {
+	var result []mediaRange
+	for _, s := range strings.Split(accept, ",") {
+		parts := strings.Split(s, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(parts[0]))
+		if mediaType == "*" {
+			mediaType = "*/*"
+		}
+
+		i := strings.Index(mediaType, "/")
+		if i <= 0 || i == len(mediaType)-1 {
+			continue
+		}
+
+		m := mediaRange{mediaType: mediaType[:i], subtype: mediaType[i+1:], q: 1}
+		valid := true
+		for _, param := range parts[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(strings.TrimSpace(name)) != "q" {
+				continue
+			}
+
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || q < 0 || q > 1 {
+				valid = false
+			}
+			m.q = q
+		}
+		if valid {
+			result = append(result, m)
+		}
+	}
+
+	// Most specific ranges first.
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].specificity() > result[j].specificity()
+	})
+	return result
+}
+
+func (m mediaRange) specificity() int {
+	switch {
+	case m.mediaType == "*":
+		return 0
+	case m.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+// quality returns the quality of the most specific media range which matches a media type, or 0.
+func quality(ranges []mediaRange, mediaType string) float64 {
+	i := strings.Index(mediaType, "/")
+	type_, subtype := mediaType[:i], mediaType[i+1:]
+
+	for _, m := range ranges {
+		switch {
+		case m.mediaType == "*",
+			m.mediaType == type_ && m.subtype == "*",
+			m.mediaType == type_ && m.subtype == subtype:
+			return m.q
+		}
+	}
+	return 0
+}
+
+// problemMediaType returns an RFC 7807 media type for JSON and XML, i.e. "application/problem+json".
+func problemMediaType(mediaType string) string {
+	switch mediaType {
+	case "application/json":
+		return "application/problem+json"
+	case "application/xml":
+		return "application/problem+xml"
+	}
+	return mediaType
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func encodeXML(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func encodeYAML(w io.Writer, v interface{}) error {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+// encodeText writes strings, bytes, errors and stringers as is, other values are formatted with fmt.
+func encodeText(w io.Writer, v interface{}) error {
+	var err error
+	switch v := v.(type) {
+	case string:
+		_, err = io.WriteString(w, v)
+	case []byte:
+		_, err = w.Write(v)
+	case error:
+		_, err = io.WriteString(w, v.Error())
+	case fmt.Stringer:
+		_, err = io.WriteString(w, v.String())
+	default:
+		_, err = fmt.Fprint(w, v)
+	}
+	return err
+}
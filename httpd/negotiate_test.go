@@ -0,0 +1,110 @@
+package httpd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type negotiateValue struct {
+	Name string `json:"name" xml:"name" yaml:"name"`
+}
+
+func (v negotiateValue) String() string {
+	return v.Name
+}
+
+func TestResp_Negotiate(t *testing.T) {
+	r := NewRouter(nil)
+	r.RegisterEncoder("application/x-custom", func(w io.Writer, v interface{}) error {
+		_, err := io.WriteString(w, "custom:"+v.(negotiateValue).Name)
+		return err
+	})
+	r.GET("/", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.Negotiate(negotiateValue{"blink"})
+	})
+
+	cases := []struct {
+		Accept      string
+		ContentType string
+		Body        string
+	}{
+		{"", "application/json; charset=utf-8", "{\"name\":\"blink\"}\n"},
+		{"*/*", "application/json; charset=utf-8", "{\"name\":\"blink\"}\n"},
+		{"application/xml", "application/xml; charset=utf-8",
+			"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<negotiateValue><name>blink</name></negotiateValue>"},
+		{"application/yaml", "application/yaml; charset=utf-8", "name: blink\n"},
+		{"text/html, text/*;q=0.5, */*;q=0.1", "text/plain; charset=utf-8", "blink"},
+		{"application/json;q=0.5, application/x-custom", "application/x-custom", "custom:blink"},
+		{"application/json;q=0, */*", "application/xml; charset=utf-8",
+			"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<negotiateValue><name>blink</name></negotiateValue>"},
+	}
+
+	for _, c := range cases {
+		req := newTestRequest(http.MethodGet, "/")
+		req.Header.Set("Accept", c.Accept)
+
+		w := serveRequest(r, req)
+		assert.Equal(t, http.StatusOK, w.Code, c.Accept)
+		assert.Equal(t, c.ContentType, w.Header().Get("Content-Type"), c.Accept)
+		assert.Equal(t, "Accept", w.Header().Get("Vary"), c.Accept)
+		assert.Equal(t, c.Body, w.Body.String(), c.Accept)
+	}
+}
+
+func TestResp_Negotiate__should_return_not_acceptable(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.Negotiate(negotiateValue{"blink"})
+	})
+
+	req := newTestRequest(http.MethodGet, "/")
+	req.Header.Set("Accept", "image/png")
+
+	w := serveRequest(r, req)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Acceptable media types: application/json, application/xml, application/yaml, text/plain",
+		w.Body.String())
+}
+
+func TestRouter_ServeHTTP__should_negotiate_problems(t *testing.T) {
+	r := NewRouter(nil)
+
+	cases := []struct {
+		Accept      string
+		ContentType string
+	}{
+		{"", "application/problem+json"},
+		{"application/problem+json", "application/problem+json"},
+		{"application/xml", "application/problem+xml"},
+		{"text/plain", "text/plain; charset=utf-8"},
+		{"text/html", "text/plain; charset=utf-8"},
+	}
+
+	for _, c := range cases {
+		req := newTestRequest(http.MethodGet, "/unknown")
+		req.Header.Set("Accept", c.Accept)
+
+		w := serveRequest(r, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, c.Accept)
+		assert.Equal(t, c.ContentType, w.Header().Get("Content-Type"), c.Accept)
+	}
+}
+
+func TestParseAccept(t *testing.T) {
+	ranges := parseAccept("text/*;q=0.3, text/html;q=0.7, text/html;level=1, */*;q=0.5, invalid, a/b;q=2")
+
+	assert.Equal(t, []mediaRange{
+		{"text", "html", 0.7},
+		{"text", "html", 1},
+		{"text", "*", 0.3},
+		{"*", "*", 0.5},
+	}, ranges)
+	assert.Equal(t, 0.7, quality(ranges, "text/html"))
+	assert.Equal(t, 0.3, quality(ranges, "text/plain"))
+	assert.Equal(t, 0.5, quality(ranges, "image/png"))
+}
@@ -0,0 +1,155 @@
+package httpd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// RecoveryPolicy configures panic recovery in a router. The zero policy recovers all panics.
+type RecoveryPolicy struct {
+	RepanicAbort  bool     // Re-panic http.ErrAbortHandler, the server aborts the response without logging.
+	RedactHeaders []string // Headers to redact in request dumps in addition to the default ones, see RequestDump.
+}
+
+// Panic is a panic recovered from middleware or a handler.
+type Panic struct {
+	Value   interface{}
+	Stack   []StackFrame // Frames from the panic to the goroutine start.
+	Request RequestDump
+}
+
+// StackFrame is a panic stack frame.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// RequestDump is a request method, path and headers with redacted secrets, i.e. Authorization and Cookie.
+type RequestDump struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"` // Without the query which can contain secrets.
+	Host   string      `json:"host"`
+	Header http.Header `json:"header"`
+}
+
+// redactedHeaders are always redacted in request dumps.
+var redactedHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-Csrf-Token",
+}
+
+const redacted = "[REDACTED]"
+
+// SetRecoveryPolicy sets a router panic recovery policy.
+func (r *Router) SetRecoveryPolicy(policy RecoveryPolicy) {
+	r.load().checkFrozen()
+	r.recovery = policy
+}
+
+// recoverPanic handles a recovered panic value, must be called in the deferred function which recovered it.
+func (r *Router) recoverPanic(ctx context.Context, route *Route, path string, req *Req, resp *Resp,
+	recovered interface{}) {
+
+	if recovered == http.ErrAbortHandler && r.recovery.RepanicAbort {
+		panic(recovered)
+	}
+	if a, ok := recovered.(abort); ok {
+		handlers, _ := r.errorHandlers(route, path)
+		handlers.Error(ctx, req, resp, a.err)
+		return
+	}
+
+	p := &Panic{
+		Value:   recovered,
+		Stack:   panicStack(),
+		Request: r.dumpRequest(req.Request),
+	}
+	handlers, _ := r.errorHandlers(route, path)
+	handlers.Panic(ctx, req, resp, p)
+}
+
+// dumpRequest returns a request dump with redacted headers.
+func (r *Router) dumpRequest(req *http.Request) RequestDump {
+	header := req.Header.Clone()
+	for _, name := range redactedHeaders {
+		if _, ok := header[name]; ok {
+			header[name] = []string{redacted}
+		}
+	}
+	for _, name := range r.recovery.RedactHeaders {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := header[name]; ok {
+			header[name] = []string{redacted}
+		}
+	}
+
+	return RequestDump{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Host:   req.Host,
+		Header: header,
+	}
+}
+
+// panicStack returns the stack frames of a panicking goroutine starting from the panicking function,
+// skips runtime frames after the panic, i.e. runtime.panicmem and runtime.sigpanic.
+func panicStack() []StackFrame {
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+
+	var result []StackFrame
+	panicking := false
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		switch {
+		case !panicking:
+			panicking = frame.Function == "runtime.gopanic"
+		case len(result) == 0 && strings.HasPrefix(frame.Function, "runtime."):
+		default:
+			result = append(result, StackFrame{frame.Function, frame.File, frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return result
+}
+
+// String returns the panic value, the request dump and the stack, i.e. for logging.
+func (p *Panic) String() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "Panic: %v\n\n%v\n", p.Value, p.Request)
+	for _, frame := range p.Stack {
+		fmt.Fprintf(&b, "%v\n\t%v:%v\n", frame.Function, frame.File, frame.Line)
+	}
+	return b.String()
+}
+
+// String returns the request line and sorted headers.
+func (d RequestDump) String() string {
+	names := make([]string, 0, len(d.Header))
+	for name := range d.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "%v %v\nHost: %v\n", d.Method, d.Path, d.Host)
+	for _, name := range names {
+		for _, value := range d.Header[name] {
+			fmt.Fprintf(&b, "%v: %v\n", name, value)
+		}
+	}
+	return b.String()
+}
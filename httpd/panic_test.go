@@ -0,0 +1,93 @@
+package httpd
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func panicHandler(ctx context.Context, req *Req, resp *Resp) error {
+	panic("failure")
+}
+
+func TestRouter_ServeHTTP__should_recover_panic_with_diagnostics(t *testing.T) {
+	r := NewRouter(nil)
+	r.SetRecoveryPolicy(RecoveryPolicy{RedactHeaders: []string{"x-tenant-secret"}})
+	r.GET("/users/:id", panicHandler)
+
+	var p *Panic
+	r.SetErrorHandlers(ErrorHandlers{
+		Panic: func(ctx context.Context, req *Req, resp *Resp, p0 *Panic) {
+			p = p0
+			resp.WriteHeader(http.StatusInternalServerError)
+		},
+	})
+
+	req := newTestRequest(http.MethodGet, "/users/1?token=secret")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-Tenant-Secret", "secret")
+	req.Header.Set("User-Agent", "test")
+
+	w := serveRequest(r, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "failure", p.Value)
+	assert.True(t, strings.HasSuffix(p.Stack[0].Function, "httpd.panicHandler"), p.Stack[0].Function)
+	assert.Equal(t, RequestDump{
+		Method: http.MethodGet,
+		Path:   "/users/1",
+		Host:   "example.com",
+		Header: http.Header{
+			"Authorization":   {"[REDACTED]"},
+			"X-Tenant-Secret": {"[REDACTED]"},
+			"User-Agent":      {"test"},
+		},
+	}, p.Request)
+	assert.NotContains(t, p.String(), "secret")
+}
+
+func TestRouter_ServeHTTP__should_skip_runtime_frames_in_panic_stack(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/", nilPanicHandler)
+
+	var p *Panic
+	r.SetErrorHandlers(ErrorHandlers{
+		Panic: func(ctx context.Context, req *Req, resp *Resp, p0 *Panic) {
+			p = p0
+			resp.WriteHeader(http.StatusInternalServerError)
+		},
+	})
+
+	serve(r, http.MethodGet, "/")
+	assert.True(t, strings.HasSuffix(p.Stack[0].Function, "httpd.nilPanicHandler"), p.Stack[0].Function)
+}
+
+func nilPanicHandler(ctx context.Context, req *Req, resp *Resp) error {
+	var params map[string]*string
+	return resp.Text(*params["id"])
+}
+
+func TestRouter_ServeHTTP__should_abort_started_responses_on_panic(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/", func(ctx context.Context, req *Req, resp *Resp) error {
+		resp.WriteHeader(http.StatusOK)
+		panic("failure")
+	})
+	r.GET("/abort", func(ctx context.Context, req *Req, resp *Resp) error {
+		panic(http.ErrAbortHandler)
+	})
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		serve(r, http.MethodGet, "/")
+	})
+
+	w := serve(r, http.MethodGet, "/abort")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	r.SetRecoveryPolicy(RecoveryPolicy{RepanicAbort: true})
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		serve(r, http.MethodGet, "/abort")
+	})
+}
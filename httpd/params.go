@@ -2,6 +2,7 @@ package httpd
 
 import "strconv"
 
+// Params are path params, their Int accessors ignore parse errors, see Value.
 type Params map[string]string
 
 func (p Params) Int(name string) int {
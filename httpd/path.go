@@ -0,0 +1,151 @@
+package httpd
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// PathPolicy configures how a router normalizes and matches request paths.
+// The zero policy matches request paths as is.
+type PathPolicy struct {
+	Clean              bool // Clean paths, i.e. "/a//b/./c/../d" => "/a/b/d".
+	StripTrailingSlash bool // Match "/a/" as "/a" when "/a/" is not found.
+	RedirectStatus     int  // Redirect to canonical paths with a status, i.e. 301 or 308, or serve them when zero.
+	CaseInsensitive    bool // Match static segments case-insensitively, segments must not differ only in case.
+	RawPath            bool // Match escaped paths and unescape segments, i.e. keeps "%2F" in params.
+	NonEmptyParams     bool // Unconstrained params do not match empty segments, i.e. in "/users//posts".
+}
+
+// SetPathPolicy sets a router path policy.
+func (r *Router) SetPathPolicy(policy PathPolicy) {
+	r.load().checkFrozen()
+	switch policy.RedirectStatus {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		panic("router: Invalid redirect status, must be 0, 301, 302, 307 or 308")
+	}
+	if policy.CaseInsensitive {
+		for _, route := range r.load().trees() {
+			route.checkCaseConflicts()
+		}
+	}
+
+	r.policy = policy
+}
+
+func (r *Router) pathOptions() matchOptions {
+	return matchOptions{
+		caseInsensitive: r.policy.CaseInsensitive,
+		rawPath:         r.policy.RawPath,
+		nonEmptyParams:  r.policy.NonEmptyParams,
+	}
+}
+
+// requestPath returns a request path to match, it is escaped when the policy uses raw paths.
+func (r *Router) requestPath(req *http.Request) string {
+	if r.policy.RawPath {
+		return req.URL.EscapedPath()
+	}
+	return req.URL.Path
+}
+
+// matchPath matches a request path in a route tree or in its compiled matcher according to
+// the router path policy, returns the canonical path and whether the request must be redirected to it.
+func (r *Router) matchPath(root *Route, route matcher, method string, p string) (
+	middleware []Middleware, handler Handler, params Params, canonical string, redirect bool, err error) {
+
+	opts := r.pathOptions()
+	canonical = p
+	if r.policy.Clean {
+		canonical = cleanPath(p)
+	}
+
+	middleware, handler, params, err = route.match(method, canonical, opts)
+	if err == ErrRouteNotFound && r.policy.StripTrailingSlash && len(canonical) > 1 && strings.HasSuffix(canonical, "/") {
+		stripped := strings.TrimRight(canonical, "/")
+		if stripped == "" {
+			stripped = "/"
+		}
+
+		m, h, ps, err1 := route.match(method, stripped, opts)
+		if err1 != ErrRouteNotFound {
+			middleware, handler, params, err = m, h, ps, err1
+			canonical = stripped
+		}
+	}
+
+	if r.policy.CaseInsensitive && r.policy.RedirectStatus != 0 && err != ErrRouteNotFound {
+		canonical = canonicalCase(root, canonical, opts)
+	}
+
+	redirect = canonical != p && r.policy.RedirectStatus != 0 && err != ErrRouteNotFound
+	return
+}
+
+// canonicalCase returns a path with static segments in the case of the matched routes.
+func canonicalCase(root *Route, p string, opts matchOptions) string {
+	routes, _, err := root.resolvePath(p, opts)
+	if err != nil {
+		return p
+	}
+
+	segments := strings.Split(p, "/")
+	for i := 1; i < len(segments) && i < len(routes); i++ {
+		route := routes[i]
+		if route.Name == catchAllSegment {
+			break
+		}
+		if !route.isStatic() {
+			continue
+		}
+
+		segment := segments[i]
+		if opts.rawPath {
+			segment, _ = unescape(segment)
+		}
+		if segment == route.Name {
+			continue
+		}
+
+		segments[i] = route.Name
+		if opts.rawPath {
+			segments[i] = url.PathEscape(route.Name)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// redirectPath redirects a request to its canonical path.
+func (r *Router) redirectPath(w http.ResponseWriter, req *http.Request, canonical string) {
+	location := canonical
+	if !r.policy.RawPath {
+		u := url.URL{Path: canonical}
+		location = u.EscapedPath()
+	}
+
+	// Prevent protocol-relative redirects, i.e. "//example.com".
+	location = "/" + strings.TrimLeft(location, "/")
+	if req.URL.RawQuery != "" {
+		location += "?" + req.URL.RawQuery
+	}
+
+	http.Redirect(w, req, location, r.policy.RedirectStatus)
+}
+
+// cleanPath returns the canonical path, keeps the trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
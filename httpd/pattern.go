@@ -0,0 +1,245 @@
+package httpd
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// patternSegment is a parsed pattern segment.
+type patternSegment struct {
+	name       string // Child name, i.e. "hello", ":", ":<int>", "v:" or "*".
+	param      string
+	constraint string
+	mixed      *segmentPattern
+	optional   bool
+}
+
+// segmentPattern is a segment with params embedded in literals, i.e. ":name.:ext" or "v:version".
+type segmentPattern struct {
+	pattern  string
+	literals []string // Literals around params, len(literals) == len(params)+1.
+	params   []string
+	re       *regexp.Regexp
+	groups   []int // Param submatch indexes.
+}
+
+// parseSegment parses a pattern segment, i.e. "hello", ":id", ":id<int>", ":page?", "*", "*filepath",
+// ":name.:ext" or "v:version<int>". Colons escaped with a backslash are literals, i.e. /v1\:batch matches "/v1:batch".
+func parseSegment(segment string) patternSegment {
+	switch {
+	case strings.HasPrefix(segment, catchAllSegment):
+		param := segment[1:]
+		switch {
+		case param == "":
+			param = catchAllParam
+		case strings.Contains(param, "<"):
+			panic(fmt.Sprintf("router: Catch-all params cannot have constraints in segment %q", segment))
+		case !isParamName(param):
+			panic(fmt.Sprintf("router: Invalid catch-all param name in segment %q", segment))
+		}
+		return patternSegment{name: catchAllSegment, param: param}
+
+	case indexParam(segment) < 0:
+		if strings.HasSuffix(segment, "?") {
+			panic(fmt.Sprintf("router: Only params can be optional in segment %q", segment))
+		}
+		return patternSegment{name: unescapeColons(segment)}
+	}
+
+	optional := strings.HasSuffix(segment, "?")
+	literals, params, paramConstraints := splitSegment(strings.TrimSuffix(segment, "?"))
+
+	// A whole segment param, i.e. ":id" or ":id<int>".
+	if len(params) == 1 && literals[0] == "" && literals[1] == "" {
+		s := patternSegment{name: paramSegment, param: params[0], constraint: paramConstraints[0], optional: optional}
+		if s.constraint != "" {
+			s.name = paramSegment + "<" + s.constraint + ">"
+		}
+		return s
+	}
+	if optional {
+		panic(fmt.Sprintf("router: Only whole segment params can be optional in segment %q", segment))
+	}
+
+	// A mixed segment, i.e. ":name.:ext".
+	name := strings.Builder{}
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	for i, param := range params {
+		name.WriteString(escapeColons(literals[i]))
+		name.WriteString(paramSegment)
+		expr.WriteString(regexp.QuoteMeta(literals[i]))
+
+		if c := paramConstraints[i]; c != "" {
+			name.WriteString("<" + c + ">")
+			if builtin, ok := constraints[c]; ok {
+				c = builtin
+			}
+			expr.WriteString(fmt.Sprintf("(?P<%v>(?:%v))", param, c))
+		} else {
+			expr.WriteString(fmt.Sprintf("(?P<%v>.+)", param))
+		}
+	}
+	name.WriteString(escapeColons(literals[len(params)]))
+	expr.WriteString(regexp.QuoteMeta(literals[len(params)]))
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		panic(fmt.Sprintf("router: Invalid param constraint in segment %q, %v", segment, err))
+	}
+
+	mixed := &segmentPattern{
+		pattern:  segment,
+		literals: literals,
+		params:   params,
+		re:       re,
+	}
+	for _, param := range params {
+		mixed.groups = append(mixed.groups, re.SubexpIndex(param))
+	}
+	return patternSegment{name: name.String(), mixed: mixed}
+}
+
+// splitSegment splits a segment into literals and params with constraints.
+func splitSegment(segment string) (literals []string, params []string, constraints []string) {
+	s := segment
+	for {
+		i := indexParam(s)
+		if i < 0 {
+			literals = append(literals, unescapeColons(s))
+			break
+		}
+		if i == 0 && len(params) > 0 {
+			panic(fmt.Sprintf("router: Params must be separated by literals in segment %q", segment))
+		}
+		literals = append(literals, unescapeColons(s[:i]))
+		s = s[i+1:]
+
+		j := 0
+		for j < len(s) && isParamByte(s[j]) {
+			j++
+		}
+		if j == 0 {
+			panic(fmt.Sprintf("router: Empty param name in segment %q", segment))
+		}
+		params = append(params, s[:j])
+		s = s[j:]
+
+		constraint := ""
+		if strings.HasPrefix(s, "<") {
+			// Constraints of the last param can contain '>'.
+			end := strings.Index(s, ">")
+			if indexParam(s) < 0 {
+				end = strings.LastIndex(s, ">")
+			}
+			if end < 0 {
+				panic(fmt.Sprintf("router: Invalid param constraint in segment %q", segment))
+			}
+
+			constraint = s[1:end]
+			s = s[end+1:]
+			if constraint == "" {
+				panic(fmt.Sprintf("router: Empty param constraint in segment %q", segment))
+			}
+		}
+		constraints = append(constraints, constraint)
+	}
+
+	for i, param := range params {
+		for _, param0 := range params[:i] {
+			if param == param0 {
+				panic(fmt.Sprintf("router: Duplicate param %q in segment %q", param, segment))
+			}
+		}
+	}
+	return
+}
+
+// match returns the segment param values or nil.
+func (p *segmentPattern) match(segment string) []string {
+	m := p.re.FindStringSubmatch(segment)
+	if m == nil {
+		return nil
+	}
+
+	values := make([]string, len(p.groups))
+	for i, group := range p.groups {
+		values[i] = m[group]
+	}
+	return values
+}
+
+// build returns an escaped segment with param values, validates the values.
+func (p *segmentPattern) build(params Params) (string, error) {
+	raw := strings.Builder{}
+	escaped := strings.Builder{}
+	for i, param := range p.params {
+		v := params[param]
+		if v == "" {
+			return "", fmt.Errorf("router: Missing param %q", param)
+		}
+
+		raw.WriteString(p.literals[i] + v)
+		escaped.WriteString(url.PathEscape(p.literals[i]) + url.PathEscape(v))
+	}
+	raw.WriteString(p.literals[len(p.params)])
+	escaped.WriteString(url.PathEscape(p.literals[len(p.params)]))
+
+	// The segment must be matched back to the same values.
+	values := p.match(raw.String())
+	for i, param := range p.params {
+		if values == nil || values[i] != params[param] {
+			return "", fmt.Errorf("router: Params do not match segment %q", p.pattern)
+		}
+	}
+	return escaped.String(), nil
+}
+
+// sameParams returns true when a segment and a route have the same param names.
+func (s patternSegment) sameParams(route *Route) bool {
+	if s.mixed == nil || route.mixed == nil {
+		return s.param == route.Param
+	}
+	return strings.Join(s.mixed.params, ",") == strings.Join(route.mixed.params, ",")
+}
+
+// indexParam returns the index of the first unescaped colon in a segment or -1.
+func indexParam(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == ':' {
+				i++
+			}
+		case ':':
+			return i
+		}
+	}
+	return -1
+}
+
+// escapeColons escapes colons in a literal, see parseSegment.
+func escapeColons(s string) string {
+	return strings.ReplaceAll(s, ":", `\:`)
+}
+
+// unescapeColons unescapes colons in a literal, see parseSegment.
+func unescapeColons(s string) string {
+	return strings.ReplaceAll(s, `\:`, ":")
+}
+
+func isParamName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isParamByte(s[i]) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isParamByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+}
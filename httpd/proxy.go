@@ -0,0 +1,278 @@
+package httpd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// forwardingHeaders are removed by ProxyHeaders after rewriting a request.
+var forwardingHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Forwarded-Proto", "X-Forwarded-Host"}
+
+// client is a resolved client address, scheme and host, see Req.ClientIP.
+type client struct {
+	ip     string
+	scheme string
+	host   string
+}
+
+// ProxyHeaderSource selects the forwarding headers which are read from trusted proxies, see SetProxyHeaderSource.
+type ProxyHeaderSource int
+
+const (
+	XForwardedHeaders ProxyHeaderSource = iota // X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host, the default.
+	ForwardedHeader                            // RFC 7239 Forwarded header.
+)
+
+// hop is a forwarding header element which describes a request received by a proxy.
+type hop struct {
+	ip    netip.Addr
+	proto string
+	host  string
+}
+
+// SetTrustedProxies sets the CIDRs or IPs of trusted proxies, i.e. "10.0.0.0/8" or "127.0.0.1".
+// Forwarding headers are read only from trusted proxies, see Req.ClientIP. Panics on invalid proxies.
+func (r *Router) SetTrustedProxies(proxies ...string) {
+	r.load().checkFrozen()
+
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				panic(fmt.Sprintf("router: Invalid trusted proxy %q", proxy))
+			}
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	r.proxies = prefixes
+}
+
+// SetProxyHeaderSource sets the forwarding headers which are read from trusted proxies. The router reads
+// only the headers of the source, the other ones are ignored, so the proxies must set or overwrite them.
+func (r *Router) SetProxyHeaderSource(source ProxyHeaderSource) {
+	r.load().checkFrozen()
+	if source != XForwardedHeaders && source != ForwardedHeader {
+		panic(fmt.Sprintf("router: Invalid proxy header source %d", source))
+	}
+	r.forwarded = source
+}
+
+// ClientIP returns the client IP. When the request comes from a trusted proxy, the IP is read from
+// the X-Forwarded-For header or from the Forwarded header, see SetProxyHeaderSource. The header is read
+// from right to left up to the first untrusted address, so clients cannot spoof it.
+func (r *Req) ClientIP() string { return r.client().ip }
+
+// Scheme returns the client request scheme, "http" or "https", it is read from the X-Forwarded-Proto
+// or Forwarded headers of trusted proxies, see ClientIP.
+func (r *Req) Scheme() string { return r.client().scheme }
+
+// ClientHost returns the client request host, it is read from the X-Forwarded-Host or Forwarded headers
+// of trusted proxies, see ClientIP.
+func (r *Req) ClientHost() string { return r.client().host }
+
+// ProxyHeaders returns middleware which rewrites the request remote address, scheme and host
+// to the client ones and removes the forwarding headers, see Req.ClientIP.
+//
+//	router.SetTrustedProxies("10.0.0.0/8")
+//	router.Middleware("/", httpd.ProxyHeaders())
+func ProxyHeaders() Middleware {
+	return func(ctx context.Context, req *Req, resp *Resp, next Handler) error {
+		c := req.client()
+		httpReq := req.Request
+
+		if ip, ok := remoteIP(httpReq.RemoteAddr); ok && ip.String() != c.ip {
+			httpReq.RemoteAddr = net.JoinHostPort(c.ip, "0")
+		}
+		httpReq.Host = c.host
+		httpReq.URL.Scheme = c.scheme
+		httpReq.URL.Host = c.host
+		for _, header := range forwardingHeaders {
+			httpReq.Header.Del(header)
+		}
+		return next(ctx, req, resp)
+	}
+}
+
+func (r *Req) client() *client {
+	if r.resolved == nil {
+		r.resolved = r.Router.resolveClient(r.Request)
+	}
+	return r.resolved
+}
+
+// resolveClient resolves a client address, scheme and host by walking forwarding headers
+// from right to left while the addresses are trusted.
+func (r *Router) resolveClient(req *http.Request) *client {
+	c := &client{scheme: "http", host: req.Host}
+	switch {
+	case req.URL.Scheme != "":
+		c.scheme = req.URL.Scheme
+	case req.TLS != nil:
+		c.scheme = "https"
+	}
+
+	ip, ok := remoteIP(req.RemoteAddr)
+	if !ok {
+		c.ip = req.RemoteAddr
+		return c
+	}
+	c.ip = ip.String()
+
+	hops := xForwardedHops(req.Header)
+	if r.forwarded == ForwardedHeader {
+		hops = forwardedHops(req.Header)
+	}
+	for i := len(hops) - 1; i >= 0 && r.trusted(ip); i-- {
+		h := hops[i]
+		if !h.ip.IsValid() {
+			break
+		}
+
+		ip = h.ip
+		c.ip = ip.String()
+		if h.proto != "" {
+			c.scheme = h.proto
+		}
+		if h.host != "" {
+			c.host = h.host
+		}
+	}
+	return c
+}
+
+func (r *Router) trusted(ip netip.Addr) bool {
+	for _, prefix := range r.proxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedHops returns the Forwarded header elements.
+func forwardedHops(header http.Header) []hop {
+	values := header.Values("Forwarded")
+	if len(values) == 0 {
+		return nil
+	}
+
+	var hops []hop
+	for _, element := range splitQuoted(strings.Join(values, ","), ',') {
+		var h hop
+		for _, pair := range splitQuoted(element, ';') {
+			key, value, _ := strings.Cut(pair, "=")
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "for":
+				h.ip, _ = hopIP(value)
+			case "proto":
+				h.proto = hopProto(value)
+			case "host":
+				h.host = hopHost(value)
+			}
+		}
+		hops = append(hops, h)
+	}
+	return hops
+}
+
+// xForwardedHops returns the X-Forwarded-For addresses with the right-aligned X-Forwarded-Proto
+// and X-Forwarded-Host values.
+func xForwardedHops(header http.Header) []hop {
+	addrs := headerList(header, "X-Forwarded-For")
+	protos := headerList(header, "X-Forwarded-Proto")
+	hosts := headerList(header, "X-Forwarded-Host")
+
+	hops := make([]hop, len(addrs))
+	for i, addr := range addrs {
+		hops[i].ip, _ = hopIP(addr)
+		hops[i].proto = hopProto(alignedValue(protos, i, len(addrs)))
+		hops[i].host = hopHost(alignedValue(hosts, i, len(addrs)))
+	}
+	return hops
+}
+
+// headerList returns comma-separated values of repeated headers.
+func headerList(header http.Header, name string) []string {
+	var list []string
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+	}
+	return list
+}
+
+// alignedValue returns a value of a list aligned to the right of n hops,
+// or the first value when the list is shorter, i.e. a single X-Forwarded-Proto.
+func alignedValue(values []string, i int, n int) string {
+	if len(values) == 0 {
+		return ""
+	}
+	if j := i - (n - len(values)); j >= 0 {
+		return values[j]
+	}
+	return values[0]
+}
+
+// splitQuoted splits a string by a separator outside quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var result []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			result = append(result, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(result, s[start:])
+}
+
+// remoteIP parses a remote address with or without a port.
+func remoteIP(addr string) (netip.Addr, bool) {
+	if ap, err := netip.ParseAddrPort(addr); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	if ip, err := netip.ParseAddr(addr); err == nil {
+		return ip.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
+
+// hopIP parses a forwarded address, i.e. 192.0.2.1, 192.0.2.1:8080 or [2001:db8::1]:8080,
+// returns false for unknown and obfuscated addresses.
+func hopIP(s string) (netip.Addr, bool) {
+	if ip, ok := remoteIP(s); ok {
+		return ip, true
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		return remoteIP(s[1 : len(s)-1])
+	}
+	return netip.Addr{}, false
+}
+
+func hopProto(s string) string {
+	switch s = strings.ToLower(s); s {
+	case "http", "https":
+		return s
+	}
+	return ""
+}
+
+func hopHost(s string) string {
+	if s == "" || strings.ContainsAny(s, "/\\@ \t") {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+package httpd
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReq_ClientIP(t *testing.T) {
+	r := NewRouter(nil)
+	r.SetTrustedProxies("10.0.0.0/8", "::1")
+
+	cases := []struct {
+		RemoteAddr string
+		Header     http.Header
+		IP         string
+		Scheme     string
+		Host       string
+	}{
+		// Untrusted remote address.
+		{"203.0.113.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.1"}}, "203.0.113.1", "http", "example.com"},
+
+		// X-Forwarded headers, spoofed addresses before untrusted ones are ignored.
+		{"10.0.0.1:1234", http.Header{
+			"X-Forwarded-For":   {"1.1.1.1, 198.51.100.1", "10.0.0.2"},
+			"X-Forwarded-Proto": {"https"},
+			"X-Forwarded-Host":  {"api.example.com"},
+		}, "198.51.100.1", "https", "api.example.com"},
+
+		// All trusted.
+		{"[::1]:1234", http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, "10.0.0.3", "http", "example.com"},
+
+		// Invalid addresses stop the walk.
+		{"10.0.0.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.1, unknown"}}, "10.0.0.1", "http", "example.com"},
+
+		// Forwarded header is ignored.
+		{"10.0.0.1:1234", http.Header{
+			"Forwarded":       {"for=1.2.3.4;proto=https;host=evil.example"},
+			"X-Forwarded-For": {"203.0.113.9"},
+		}, "203.0.113.9", "http", "example.com"},
+		{"10.0.0.1:1234", http.Header{
+			"Forwarded": {"for=1.2.3.4;proto=https;host=evil.example"},
+		}, "10.0.0.1", "http", "example.com"},
+	}
+
+	for _, c := range cases {
+		httpReq := newTestRequest(http.MethodGet, "/")
+		httpReq.Host = "example.com"
+		httpReq.RemoteAddr = c.RemoteAddr
+		httpReq.Header = c.Header
+
+		req := newReq(r, httpReq, nil)
+		assert.Equal(t, c.IP, req.ClientIP(), c.Header)
+		assert.Equal(t, c.Scheme, req.Scheme(), c.Header)
+		assert.Equal(t, c.Host, req.ClientHost(), c.Header)
+	}
+
+	httpReq := newTestRequest(http.MethodGet, "/")
+	httpReq.TLS = &tls.ConnectionState{}
+	assert.Equal(t, "https", newReq(r, httpReq, nil).Scheme())
+
+	assert.Panics(t, func() { NewRouter(nil).SetTrustedProxies("10.0.0.0/33") })
+}
+
+func TestReq_ClientIP__should_read_forwarded_header(t *testing.T) {
+	r := NewRouter(nil)
+	r.SetTrustedProxies("10.0.0.0/8", "10::/16")
+	r.SetProxyHeaderSource(ForwardedHeader)
+
+	cases := []struct {
+		Header http.Header
+		IP     string
+		Scheme string
+		Host   string
+	}{
+		// X-Forwarded headers are ignored.
+		{http.Header{
+			"Forwarded":       {"for=1.2.3.4;proto=https;host=evil.example"},
+			"X-Forwarded-For": {"203.0.113.9"},
+		}, "1.2.3.4", "https", "evil.example"},
+		{http.Header{
+			"X-Forwarded-For":   {"203.0.113.9"},
+			"X-Forwarded-Proto": {"https"},
+		}, "10.0.0.1", "http", "example.com"},
+
+		// Spoofed elements before untrusted ones are ignored.
+		{http.Header{
+			"Forwarded": {`for=198.51.100.1;proto=https;host="shop.example.com", for="[10::1]:8080"`, "for=10.0.0.2"},
+		}, "198.51.100.1", "https", "shop.example.com"},
+		{http.Header{
+			"Forwarded": {`for=1.1.1.1;host=evil.example, for="[2001:db8::1]:4711";proto=https;host=shop.example.com`},
+		}, "2001:db8::1", "https", "shop.example.com"},
+	}
+
+	for _, c := range cases {
+		httpReq := newTestRequest(http.MethodGet, "/")
+		httpReq.Host = "example.com"
+		httpReq.RemoteAddr = "10.0.0.1:1234"
+		httpReq.Header = c.Header
+
+		req := newReq(r, httpReq, nil)
+		assert.Equal(t, c.IP, req.ClientIP(), c.Header)
+		assert.Equal(t, c.Scheme, req.Scheme(), c.Header)
+		assert.Equal(t, c.Host, req.ClientHost(), c.Header)
+	}
+
+	assert.Panics(t, func() { NewRouter(nil).SetProxyHeaderSource(ProxyHeaderSource(2)) })
+}
+
+func TestProxyHeaders(t *testing.T) {
+	r := NewRouter(nil)
+	r.SetTrustedProxies("10.0.0.0/8")
+	r.Middleware("/", ProxyHeaders())
+	r.GET("/", WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "198.51.100.1:0", req.RemoteAddr)
+		assert.Equal(t, "api.example.com", req.Host)
+		assert.Equal(t, "https", req.URL.Scheme)
+		assert.Empty(t, req.Header.Get("X-Forwarded-For"))
+		w.Write([]byte("OK"))
+	})))
+	r.GET("/ip", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.Text(req.ClientIP())
+	})
+
+	httpReq := newTestRequest(http.MethodGet, "/")
+	httpReq.RemoteAddr = "10.0.0.1:1234"
+	httpReq.Header.Set("X-Forwarded-For", "198.51.100.1")
+	httpReq.Header.Set("X-Forwarded-Proto", "https")
+	httpReq.Header.Set("X-Forwarded-Host", "api.example.com")
+	w := serveRequest(r, httpReq)
+	assert.Equal(t, "OK", w.Body.String())
+
+	httpReq = newTestRequest(http.MethodGet, "/ip")
+	httpReq.RemoteAddr = "203.0.113.1:1234"
+	httpReq.Header.Set("X-Forwarded-For", "198.51.100.1")
+	w = serveRequest(r, httpReq)
+	assert.Equal(t, "203.0.113.1", w.Body.String())
+}
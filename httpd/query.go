@@ -0,0 +1,162 @@
+package httpd
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Query is a parsed request query, it is separate from form values which include the body.
+// Repeated keys are available in the embedded values, i.e. q.Values["tag"].
+//
+//	page := req.Query().Value("page").Default("1").MustInt()
+//	tags := req.Query().List("tag")                          // ?tag=a,b&tag=c is [a b c]
+//	filters := req.Query().Map("filter")                     // ?filter[status]=active is {status: active}
+//	sort, err := req.Query().Sort("sort", "name", "created") // ?sort=-created,name
+type Query struct {
+	url.Values
+}
+
+// SortField is a sort field, see Query.Sort.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Query returns the parsed request query.
+func (r *Req) Query() Query {
+	if r.query.Values == nil {
+		r.query = Query{r.URL.Query()}
+	}
+	return r.query
+}
+
+// URLWithQuery returns the request path and query with modified query params, nil values delete params,
+// i.e. pagination links.
+//
+//	next := req.URLWithQuery(url.Values{"page": {"3"}, "cursor": nil})
+func (r *Req) URLWithQuery(params url.Values) string {
+	query := r.URL.Query()
+	for key, values := range params {
+		if values == nil {
+			query.Del(key)
+			continue
+		}
+		query[key] = values
+	}
+
+	u := url.URL{Path: r.URL.Path, RawPath: r.URL.RawPath, RawQuery: query.Encode()}
+	return u.String()
+}
+
+// Value returns the first query value.
+func (q Query) Value(key string) Value {
+	values, ok := q.Values[key]
+	v := Value{Source: "query", Name: key, Exists: ok}
+	if len(values) > 0 {
+		v.Raw = values[0]
+	}
+	return v
+}
+
+// List returns repeated and comma-separated query values, skips empty values.
+func (q Query) List(key string) []string {
+	var list []string
+	for _, value := range q.Values[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// Int64s returns repeated and comma-separated integers, see List.
+func (q Query) Int64s(key string) ([]int64, error) {
+	list := q.List(key)
+	result := make([]int64, 0, len(list))
+	for _, item := range list {
+		i, err := Value{Source: "query", Name: key, Raw: item, Exists: true}.Int64()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+// Map returns nested query values with a prefix, i.e. filter[status]=active is {"status": "active"}.
+func (q Query) Map(prefix string) map[string]string {
+	result := make(map[string]string)
+	for key, values := range q.Values {
+		rest, ok := strings.CutPrefix(key, prefix+"[")
+		if !ok || len(values) == 0 || !strings.HasSuffix(rest, "]") {
+			continue
+		}
+
+		name := rest[:len(rest)-1]
+		if name == "" || strings.ContainsAny(name, "[]") {
+			continue
+		}
+		result[name] = values[0]
+	}
+	return result
+}
+
+// Sort parses comma-separated sort fields, returns a bad request error when a field is not allowed.
+// Fields are ascending by default, descending fields start with a minus or end with :desc,
+// i.e. sort=-created,name or sort=created:desc,name:asc. Empty allowed fields allow all fields.
+func (q Query) Sort(key string, allowed ...string) ([]SortField, error) {
+	var result []SortField
+	for _, item := range q.List(key) {
+		field := SortField{Field: item}
+		switch {
+		case strings.HasPrefix(item, "-"):
+			field = SortField{Field: item[1:], Desc: true}
+		case strings.HasPrefix(item, "+"):
+			field = SortField{Field: item[1:]}
+		default:
+			if name, order, ok := strings.Cut(item, ":"); ok {
+				order = strings.ToLower(order)
+				if order != "asc" && order != "desc" {
+					v := Value{Source: "query", Name: key, Raw: item, Exists: true}
+					return nil, v.invalid("sorted in asc or desc order")
+				}
+				field = SortField{Field: name, Desc: order == "desc"}
+			}
+		}
+
+		if !sortAllowed(allowed, field.Field) {
+			v := Value{Source: "query", Name: key, Raw: item, Exists: true}
+			if len(allowed) == 0 {
+				return nil, v.invalid("sorted by a field")
+			}
+			return nil, v.invalid("sorted by " + strings.Join(allowed, ", "))
+		}
+		result = append(result, field)
+	}
+	return result, nil
+}
+
+// String returns a field with a minus when descending, i.e. -created.
+func (f SortField) String() string {
+	if f.Desc {
+		return "-" + f.Field
+	}
+	return f.Field
+}
+
+func sortAllowed(allowed []string, field string) bool {
+	if field == "" {
+		return false
+	}
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == field {
+			return true
+		}
+	}
+	return false
+}
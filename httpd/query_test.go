@@ -0,0 +1,68 @@
+package httpd
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReq_Query(t *testing.T) {
+	httpReq := newBodyRequest(http.MethodPost, "/users?page=2&limit=x&tag=a,b&tag=c&id=1&id=2,3&filter[status]=active&filter[]=x",
+		"application/x-www-form-urlencoded", "page=5")
+	q := newReq(NewRouter(nil), httpReq, nil).Query()
+
+	page, err := q.Value("page").Int()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, page)
+
+	_, err = q.Value("limit").Int()
+	assert.EqualError(t, err, `Bad request: Invalid query "limit", must be an integer`)
+
+	_, err = q.Value("unknown").Int()
+	assert.EqualError(t, err, `Bad request: Missing query "unknown"`)
+
+	assert.Equal(t, []string{"a,b", "c"}, q.Values["tag"])
+	assert.Equal(t, []string{"a", "b", "c"}, q.List("tag"))
+
+	ids, err := q.Int64s("id")
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, ids)
+
+	_, err = q.Int64s("tag")
+	assert.EqualError(t, err, `Bad request: Invalid query "tag", must be an integer`)
+
+	assert.Equal(t, map[string]string{"status": "active"}, q.Map("filter"))
+}
+
+func TestQuery_Sort(t *testing.T) {
+	q := Query{url.Values{
+		"sort":    {"-created,name", "updated:desc"},
+		"invalid": {"password"},
+		"order":   {"name:up"},
+	}}
+
+	fields, err := q.Sort("sort", "name", "created", "updated")
+	assert.Nil(t, err)
+	assert.Equal(t, []SortField{{Field: "created", Desc: true}, {Field: "name"}, {Field: "updated", Desc: true}}, fields)
+	assert.Equal(t, "-created", fields[0].String())
+
+	_, err = q.Sort("invalid", "name", "created")
+	assert.EqualError(t, err, `Bad request: Invalid query "invalid", must be sorted by name, created`)
+
+	_, err = q.Sort("order")
+	assert.EqualError(t, err, `Bad request: Invalid query "order", must be sorted in asc or desc order`)
+
+	fields, err = q.Sort("unknown")
+	assert.Nil(t, err)
+	assert.Nil(t, fields)
+}
+
+func TestReq_URLWithQuery(t *testing.T) {
+	req := newReq(NewRouter(nil), newTestRequest(http.MethodGet, "/users?page=2&limit=10&cursor=abc"), nil)
+
+	assert.Equal(t, "/users?limit=10&page=3", req.URLWithQuery(url.Values{"page": {"3"}, "cursor": nil}))
+	assert.Equal(t, "/users?cursor=abc&limit=10&page=2&tag=a&tag=b", req.URLWithQuery(url.Values{"tag": {"a", "b"}}))
+	assert.Equal(t, "/users?page=2&limit=10&cursor=abc", req.URL.String())
+}
@@ -2,7 +2,6 @@ package httpd
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 	"strconv"
 )
@@ -11,13 +10,19 @@ type Req struct {
 	*http.Request
 	Router *Router
 	Params Params
+
+	query      Query // Parsed query, see Query.
+	bodyPolicy BodyPolicy
+	done       []func() // Cleanup functions called when the handler returns, i.e. removing spooled files.
+	resolved   *client  // Resolved client address, scheme and host, see ClientIP.
 }
 
 func newReq(r *Router, r0 *http.Request, params Params) *Req {
 	return &Req{
-		Router:  r,
-		Request: r0,
-		Params:  params,
+		Router:     r,
+		Request:    r0,
+		Params:     params,
+		bodyPolicy: r.body,
 	}
 }
 
@@ -37,6 +42,23 @@ func (r *Req) Param(param string) string {
 	return r.Params[param]
 }
 
+// onDone adds a function which is called when the handler returns.
+func (r *Req) onDone(fn func()) {
+	r.done = append(r.done, fn)
+}
+
+func (r *Req) cleanup() {
+	for i := len(r.done) - 1; i >= 0; i-- {
+		r.done[i]()
+	}
+	r.done = nil
+}
+
+// URLFor builds a path to a named route, see Router.URL.
+func (r *Req) URLFor(name string, params Params) (string, error) {
+	return r.Router.URL(name, params)
+}
+
 func (r *Req) FormInt(key string) int {
 	v := r.FormValue(key)
 	i, _ := strconv.ParseInt(v, 10, 64)
@@ -55,12 +77,14 @@ func (r *Req) FormInt64(key string) int64 {
 	return i
 }
 
-// DecodeJSON decodes a JSON body into a given destination.
+// DecodeJSON decodes a JSON or gzipped JSON body into a given destination and validates it, see Validate.
+// It returns 415 errors for other content types, 413 errors for bodies larger than the max body size
+// and bad request errors with line and column positions for invalid JSON, see BodyPolicy.
 func (r *Req) DecodeJSON(dst interface{}) error {
-	if err := json.NewDecoder(r.Body).Decode(&dst); err != nil {
-		return NewBadRequestError(err.Error())
+	if err := r.decodeJSON(dst); err != nil {
+		return err
 	}
-	return nil
+	return Validate(dst)
 }
 
 func (r *Req) WebSocket(ctx context.Context, resp *Resp) (*WebSocket, error) {
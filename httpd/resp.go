@@ -1,9 +1,12 @@
 package httpd
 
 import (
+	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"time"
@@ -15,24 +18,63 @@ type Resp struct {
 
 	Status     int
 	TotalBytes int64
+
+	discard bool   // Discard the body but keep the headers, i.e. in HEAD responses.
+	accept  string // Request Accept header, see Negotiate.
 }
 
-func newResp(router *Router, w http.ResponseWriter) *Resp {
+func newResp(router *Router, w http.ResponseWriter, req *http.Request) *Resp {
 	return &Resp{
 		Router:         router,
 		ResponseWriter: w,
+		discard:        req.Method == HEAD,
+		accept:         req.Header.Get("Accept"),
 	}
 }
 
 func (r *Resp) Write(b []byte) (int, error) {
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	if r.discard {
+		return len(b), nil
+	}
+
 	n, err := r.ResponseWriter.Write(b)
 	r.TotalBytes += int64(n)
 	return n, err
 }
 
+// WriteHeader writes the status code, records the first non-informational status.
 func (r *Resp) WriteHeader(status int) {
 	r.ResponseWriter.WriteHeader(status)
-	r.Status = status
+	if r.Status == 0 && status >= 200 {
+		r.Status = status
+	}
+}
+
+// Flush implements http.Flusher when the underlying writer supports it.
+func (r *Resp) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		if r.Status == 0 {
+			r.Status = http.StatusOK
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, returns an error when the underlying writer does not support it.
+func (r *Resp) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("router: Response writer does not implement http.Hijacker")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying writer, it is used by http.ResponseController.
+func (r *Resp) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
 }
 
 func (r *Resp) SetContentType(ctype string) {
@@ -57,7 +99,7 @@ func (r *Resp) Text(text string) error {
 func (r *Resp) TextStatus(text string, status int) error {
 	r.SetContentType("text/plain; charset=utf-8")
 	r.SetContentLength(int64(len(text)))
-	r.WriteHeader(http.StatusOK)
+	r.WriteHeader(status)
 	r.Write([]byte(text))
 	return nil
 }
@@ -85,6 +127,28 @@ func (r *Resp) ErrorInternal() error {
 	return r.Error("Internal server error", http.StatusInternalServerError)
 }
 
+// Problem serves an error as an RFC 7807 problem with an encoder selected by the Accept header,
+// i.e. as application/problem+json, or as text when no encoder is acceptable. Zero statuses are served as 500.
+func (r *Resp) Problem(e *Error) error {
+	p := newProblem(e)
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+		p.Title = http.StatusText(p.Status)
+	}
+
+	r.Header().Add("Vary", "Accept")
+	enc, ok := negotiate(r.Router.encoders, r.accept, true)
+	if !ok {
+		enc = textEncoder
+	}
+
+	contentType := enc.contentType
+	if mediaType := problemMediaType(enc.mediaType); mediaType != enc.mediaType {
+		contentType = mediaType
+	}
+	return r.encode(enc, contentType, p, p.Status)
+}
+
 // JSON
 
 // JSON serves an OK JSON response, returns an error on JSON encoding errors, when no response is written yet.
@@ -107,7 +171,7 @@ func (r *Resp) JSONStatus(v interface{}, status int) error {
 func (r *Resp) JSONBytes(bytes []byte, status int) error {
 	r.SetContentType("application/json; charset=utf-8")
 	r.SetContentLength(int64(len(bytes)))
-	r.WriteHeader(http.StatusOK)
+	r.WriteHeader(status)
 	r.Write(bytes)
 	return nil
 }
@@ -0,0 +1,27 @@
+package httpd
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResp__should_write_status(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/text", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.TextStatus("created", http.StatusCreated)
+	})
+	r.GET("/json", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.JSONBytes([]byte(`{}`), http.StatusAccepted)
+	})
+
+	w := serve(r, http.MethodGet, "/text")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "created", w.Body.String())
+
+	w = serve(r, http.MethodGet, "/json")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, `{}`, w.Body.String())
+}
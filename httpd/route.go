@@ -1,20 +1,31 @@
 package httpd
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 )
 
 const (
-	ALL    = ""
-	HEAD   = "HEAD"
-	GET    = "GET"
-	POST   = "POST"
-	PUT    = "PUT"
-	DELETE = "DELETE"
+	ALL     = ""
+	HEAD    = "HEAD"
+	GET     = "GET"
+	POST    = "POST"
+	PUT     = "PUT"
+	PATCH   = "PATCH"
+	DELETE  = "DELETE"
+	OPTIONS = "OPTIONS"
+	TRACE   = "TRACE"
+	CONNECT = "CONNECT"
 )
 
+// methods are reported in the Allow header for routes with ALL handlers.
+var methods = []string{CONNECT, DELETE, GET, HEAD, OPTIONS, PATCH, POST, PUT, TRACE}
+
 const (
 	paramSegment    = ":"
 	catchAllSegment = "*"
@@ -22,34 +33,72 @@ const (
 )
 
 // Route is a linked tree of routes with handlers and middleware.
+//
+// OPTIONS requests are answered automatically with an Allow header
+// unless a route has an OPTIONS or an ALL handler. HEAD requests fall back
+// to GET handlers, the router discards HEAD response bodies.
+//
+// Param segments can have constraints, i.e. :id<int>, :uid<uuid> or :slug<[a-z0-9-]+>,
+// params can be embedded in segments, i.e. :name.:ext or v:version<int>, a catch-all
+// can be named, i.e. *filepath, the last param can be optional, i.e. /posts/:page?.
+// Colons escaped with a backslash are literals, i.e. /v1\:batch or /jobs/:id\:cancel.
+// A segment is resolved in this order: a static child, mixed segment children and then
+// constrained param children in the order they were added, an unconstrained param child
+// and a catch-all child. Unconstrained params match empty segments unless the router
+// path policy disallows it, see PathPolicy.NonEmptyParams.
+// A catch-all must be the last segment, it matches the rest of the path including slashes.
+//
+// An optional param route is matched without the param when its parent has no handlers,
+// registering handlers on both the parent and the optional route panics.
+//
+// Middleware is executed in this order: subtree middleware from the root to the endpoint,
+// endpoint middleware, method middleware of the handler method, the handler. Middleware
+// in each scope is executed in the order it was added. HEAD requests which fall back
+// to GET handlers use GET method middleware, automatic OPTIONS responses use OPTIONS
+// method middleware.
 type Route struct {
-	Name     string
-	Param    string
-	Middle   []Middleware
-	Handlers map[string]Handler // map[method]Handler
-	Children map[string]*Route  // map[pattern]*Route
+	Name           string
+	Param          string
+	Constraint     string // Optional param constraint, a built-in name or a regular expression.
+	Alias          string // Optional unique route name, see Named and URL.
+	Optional       bool   // The param can be omitted when it is the last segment.
+	Middle         []Middleware
+	EndpointMiddle []Middleware            // Applied only to the route handlers after Middle.
+	MethodMiddle   map[string][]Middleware // map[method][]Middleware, applied only to the method handler after EndpointMiddle.
+	Handlers       map[string]Handler      // map[method]Handler
+	Children       map[string]*Route       // map[pattern]*Route
+	Errors         *ErrorHandlers          // Optional subtree error handlers, see ErrorHandlers.
+
+	re       *regexp.Regexp  // Compiled constraint.
+	mixed    *segmentPattern // Params embedded in the segment.
+	patterns []*Route        // Mixed and then constrained param children in the order they were added.
 }
 
 // NewRoute creates a root route.
 func NewRoute() *Route {
-	return newRoute("", "")
+	return newRoute(patternSegment{})
 }
 
-func newRoute(name string, param string) *Route {
-	return &Route{
-		Name:     name,
-		Param:    param,
+func newRoute(segment patternSegment) *Route {
+	route := &Route{
+		Name:     segment.name,
 		Handlers: make(map[string]Handler),
 		Children: make(map[string]*Route),
 	}
+	route.setSegment(segment)
+	return route
 }
 
-func (r *Route) ALL(pattern string, h Handler)    { r.Handler(ALL, pattern, h) }
-func (r *Route) HEAD(pattern string, h Handler)   { r.Handler(HEAD, pattern, h) }
-func (r *Route) GET(pattern string, h Handler)    { r.Handler(GET, pattern, h) }
-func (r *Route) POST(pattern string, h Handler)   { r.Handler(POST, pattern, h) }
-func (r *Route) PUT(pattern string, h Handler)    { r.Handler(PUT, pattern, h) }
-func (r *Route) DELETE(pattern string, h Handler) { r.Handler(DELETE, pattern, h) }
+func (r *Route) ALL(pattern string, h Handler) *Route     { return r.Handler(ALL, pattern, h) }
+func (r *Route) HEAD(pattern string, h Handler) *Route    { return r.Handler(HEAD, pattern, h) }
+func (r *Route) GET(pattern string, h Handler) *Route     { return r.Handler(GET, pattern, h) }
+func (r *Route) POST(pattern string, h Handler) *Route    { return r.Handler(POST, pattern, h) }
+func (r *Route) PUT(pattern string, h Handler) *Route     { return r.Handler(PUT, pattern, h) }
+func (r *Route) PATCH(pattern string, h Handler) *Route   { return r.Handler(PATCH, pattern, h) }
+func (r *Route) DELETE(pattern string, h Handler) *Route  { return r.Handler(DELETE, pattern, h) }
+func (r *Route) OPTIONS(pattern string, h Handler) *Route { return r.Handler(OPTIONS, pattern, h) }
+func (r *Route) TRACE(pattern string, h Handler) *Route   { return r.Handler(TRACE, pattern, h) }
+func (r *Route) CONNECT(pattern string, h Handler) *Route { return r.Handler(CONNECT, pattern, h) }
 
 func (r *Route) Static(pattern string, dir http.Dir) {
 	if !strings.HasSuffix(pattern, "/*") {
@@ -70,30 +119,43 @@ func (r *Route) Add(pattern string, child *Route) {
 	// Get the last segment in path.
 	// It is the child name or param.
 	i := strings.LastIndex(pattern, "/")
-	name, param := parseNameParam(pattern[i+1:])
+	segment := parseSegment(pattern[i+1:])
 	pattern = pattern[:i]
 
 	// Resolve the child parent.
 	route := r.makePath(pattern)
 
 	// Prevent duplicate children.
-	if _, ok := route.Children[name]; ok {
+	if _, ok := route.Children[segment.name]; ok {
 		panic("router: Duplicate child")
 	}
 
 	// Add the child and set its name and param.
-	route.Children[name] = child
-	child.Name = name
-	child.Param = param
+	child.Name = segment.name
+	child.setSegment(segment)
+	if child.Optional {
+		route.checkOptional(child)
+	}
+	route.addChild(child)
 }
 
-func (r *Route) Handler(method string, p string, h Handler) {
+// Handler adds a method handler to a pattern and returns the pattern route.
+func (r *Route) Handler(method string, p string, h Handler) *Route {
 	if h == nil {
 		panic("router: Nil handler")
 	}
 
-	route := r.makePath(p)
 	method = strings.ToUpper(method)
+	if method != ALL && !validMethod(method) {
+		panic(fmt.Sprintf("router: Invalid method %q", method))
+	}
+
+	route := r.makePath(p)
+	for _, child := range route.Children {
+		if child.Optional && len(child.Handlers) > 0 {
+			panic("router: Handlers conflict with an optional param child")
+		}
+	}
 
 	switch method {
 	case ALL:
@@ -113,43 +175,263 @@ func (r *Route) Handler(method string, p string, h Handler) {
 
 		route.Handlers[method] = h
 	}
+	return route
+}
+
+// Methods adds a handler for multiple methods to a pattern and returns the pattern route.
+func (r *Route) Methods(methods []string, p string, h Handler) *Route {
+	if len(methods) == 0 {
+		panic("router: No methods")
+	}
+
+	var route *Route
+	for _, method := range methods {
+		if method == ALL {
+			panic("router: ALL method in a method list")
+		}
+		route = r.Handler(method, p, h)
+	}
+	return route
+}
+
+// Named sets the route name which is used to build URLs, i.e.
+//
+//	router.GET("/users/:id", handler).Named("user")
+//	router.URL("user", Params{"id": "123"}) // /users/123
+//
+// Names must be unique, the router panics on duplicate names when it is frozen.
+func (r *Route) Named(alias string) *Route {
+	if alias == "" {
+		panic("router: Empty route name")
+	}
+
+	r.Alias = alias
+	return r
+}
+
+// URL builds a path to a named route, fills in and escapes its params.
+func (r *Route) URL(alias string, params Params) (string, error) {
+	paths := r.findAlias(alias, nil, nil)
+	switch len(paths) {
+	case 0:
+		return "", ErrRouteNameNotFound
+	case 1:
+	default:
+		return "", fmt.Errorf("router: Duplicate route name %q", alias)
+	}
+
+	path := paths[0]
+	if len(path) == 0 {
+		return "/", nil
+	}
+
+	b := strings.Builder{}
+	for i, route := range path {
+		if route.Optional && i == len(path)-1 && params[route.Param] == "" {
+			break
+		}
+		b.WriteString("/")
+
+		switch {
+		case route.Name == catchAllSegment:
+			v, ok := params[route.Param]
+			if !ok {
+				return "", fmt.Errorf("router: Missing param %q", route.Param)
+			}
+			for i, s := range strings.Split(v, "/") {
+				if i > 0 {
+					b.WriteString("/")
+				}
+				b.WriteString(url.PathEscape(s))
+			}
+
+		case route.Param != "":
+			v, ok := params[route.Param]
+			if !ok || v == "" {
+				return "", fmt.Errorf("router: Missing param %q", route.Param)
+			}
+			if route.re != nil && !route.re.MatchString(v) {
+				return "", fmt.Errorf("router: Param %q does not match constraint %q", route.Param, route.Constraint)
+			}
+			b.WriteString(url.PathEscape(v))
+
+		case route.mixed != nil:
+			segment, err := route.mixed.build(params)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(segment)
+
+		default:
+			b.WriteString(url.PathEscape(route.Name))
+		}
+	}
+	if b.Len() == 0 {
+		return "/", nil
+	}
+	return b.String(), nil
 }
 
+// checkAliases panics when a route name is already used in a tree or in other trees.
+func (r *Route) checkAliases(seen map[string]bool) {
+	if r.Alias != "" {
+		if seen[r.Alias] {
+			panic(fmt.Sprintf("router: Duplicate route name %q", r.Alias))
+		}
+		seen[r.Alias] = true
+	}
+
+	for _, child := range r.Children {
+		child.checkAliases(seen)
+	}
+}
+
+// findAlias returns paths to all routes with a given alias, excluding the receiver from the paths.
+func (r *Route) findAlias(alias string, path []*Route, result [][]*Route) [][]*Route {
+	if r.Alias == alias {
+		found := make([]*Route, len(path))
+		copy(found, path)
+		result = append(result, found)
+	}
+
+	for _, child := range r.Children {
+		result = child.findAlias(alias, append(path, child), result)
+	}
+	return result
+}
+
+// Middleware adds middleware to a pattern subtree, it applies to the pattern and all its children.
 func (r *Route) Middleware(pattern string, m Middleware) {
 	if m == nil {
 		panic("router: Nil middleware")
 	}
 
 	route := r.makePath(pattern)
-	route.Middle = append(r.Middle, m)
+	route.Middle = append(route.Middle, m)
+}
+
+// EndpointMiddleware adds middleware which applies only to the pattern handlers, not to its children.
+func (r *Route) EndpointMiddleware(pattern string, m Middleware) {
+	if m == nil {
+		panic("router: Nil middleware")
+	}
+
+	route := r.makePath(pattern)
+	route.EndpointMiddle = append(route.EndpointMiddle, m)
+}
+
+// MethodMiddleware adds middleware which applies only to the pattern method handler,
+// i.e. to POST but not to GET.
+func (r *Route) MethodMiddleware(method string, pattern string, m Middleware) {
+	if m == nil {
+		panic("router: Nil middleware")
+	}
+
+	method = strings.ToUpper(method)
+	if method != ALL && !validMethod(method) {
+		panic(fmt.Sprintf("router: Invalid method %q", method))
+	}
+
+	route := r.makePath(pattern)
+	route.addMethodMiddleware(method, m)
+}
+
+// Chain returns the function names of the effective middleware chain and the handler for a method and a path.
+func (r *Route) Chain(method string, path string) ([]string, error) {
+	middleware, handler, _, err := r.Match(method, path)
+	if err != nil {
+		return nil, err
+	}
+	return chainNames(middleware, handler), nil
 }
 
 func (r *Route) Match(method string, path string) ([]Middleware, Handler, Params, error) {
-	routes, params, err := r.Resolve(path)
+	return r.match(method, path, matchOptions{})
+}
+
+func (r *Route) match(method string, path string, opts matchOptions) ([]Middleware, Handler, Params, error) {
+	routes, params, err := r.resolvePath(path, opts)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
 	last := routes[len(routes)-1]
-	handler := last.Handlers[method]
+	handler, key := last.methodHandler(method)
 	if handler == nil {
-		handler = last.Handlers[ALL]
-		if handler == nil {
-			return nil, nil, nil, ErrMethodNotAllowed
-		}
+		return nil, nil, nil, ErrMethodNotAllowed
 	}
 
 	middleware := []Middleware{}
 	for _, route := range routes {
 		middleware = append(middleware, route.Middle...)
 	}
+	middleware = append(middleware, last.EndpointMiddle...)
+	middleware = append(middleware, last.MethodMiddle[key]...)
 
 	return middleware, handler, params, nil
 }
 
+// methodHandler returns a handler for a method and its key in Handlers and MethodMiddle,
+// the key is OPTIONS for the automatic OPTIONS handler.
+func (r *Route) methodHandler(method string) (Handler, string) {
+	if h, ok := r.Handlers[method]; ok {
+		return h, method
+	}
+	if h, ok := r.Handlers[ALL]; ok {
+		return h, ALL
+	}
+	if method == HEAD {
+		if h, ok := r.Handlers[GET]; ok {
+			return h, GET
+		}
+	}
+	if method == OPTIONS && len(r.Handlers) > 0 {
+		return newOptionsHandler(r.Allow()), OPTIONS
+	}
+	return nil, ""
+}
+
+// Allow returns the sorted route methods including the automatic OPTIONS method,
+// or nil when the route has no handlers.
+func (r *Route) Allow() []string {
+	if len(r.Handlers) == 0 {
+		return nil
+	}
+	if _, ok := r.Handlers[ALL]; ok {
+		return methods
+	}
+
+	result := make([]string, 0, len(r.Handlers)+1)
+	for method := range r.Handlers {
+		result = append(result, method)
+	}
+	if _, ok := r.Handlers[OPTIONS]; !ok {
+		result = append(result, OPTIONS)
+	}
+	_, head := r.Handlers[HEAD]
+	if _, get := r.Handlers[GET]; get && !head {
+		result = append(result, HEAD)
+	}
+
+	sort.Strings(result)
+	return result
+}
+
 func (r *Route) Resolve(path string) ([]*Route, Params, error) {
+	return r.resolvePath(path, matchOptions{})
+}
+
+// matchOptions are path matching options, see PathPolicy.
+type matchOptions struct {
+	caseInsensitive bool // Match static segments case-insensitively.
+	rawPath         bool // The path is escaped, unescape its segments.
+	nonEmptyParams  bool // Unconstrained params do not match empty segments.
+}
+
+func (r *Route) resolvePath(path string, opts matchOptions) ([]*Route, Params, error) {
 	if path == "" || path == "/" {
-		return []*Route{r}, Params{}, nil
+		routes, _ := r.resolve(nil, []*Route{r}, nil, opts)
+		return routes, Params{}, nil
 	}
 
 	// Add the first slash if absent.
@@ -158,43 +440,174 @@ func (r *Route) Resolve(path string) ([]*Route, Params, error) {
 		path = "/" + path
 	}
 
-	route := r
 	segments := strings.Split(path, "/")[1:]
-
-	routes := []*Route{route}
 	params := Params{}
 
-	// Traverse the tree.
-	for len(segments) > 0 {
-		segment := segments[0]
+	routes, ok := r.resolve(segments, []*Route{r}, params, opts)
+	if !ok {
+		return nil, nil, ErrRouteNotFound
+	}
+	return routes, params, nil
+}
 
-		// A static segment, i.e. "hello" in /hello.
-		child, ok := route.Children[segment]
-		if !ok {
-			// A param segment, i.e. ":param".
-			child, ok = route.Children[paramSegment]
-			if !ok {
-				// A catch all segment, i.e. "*".
-				child, ok = route.Children[catchAllSegment]
+// resolve recursively traverses the tree, falls through to the next candidate child when
+// a subtree does not match the remaining segments.
+func (r *Route) resolve(segments []string, routes []*Route, params Params, opts matchOptions) ([]*Route, bool) {
+	if len(segments) == 0 {
+		if child := r.optionalChild(); child != nil {
+			return append(routes, child), true
+		}
+		return routes, true
+	}
 
-				if !ok {
-					return nil, nil, ErrRouteNotFound
-				}
+	segment := segments[0]
+	if opts.rawPath {
+		var ok bool
+		if segment, ok = unescape(segment); !ok {
+			return nil, false
+		}
+	}
+
+	// A static segment, i.e. "hello" in /hello.
+	if child := r.staticChild(segment, opts.caseInsensitive); child != nil {
+		if result, ok := child.resolve(segments[1:], append(routes, child), params, opts); ok {
+			return result, true
+		}
+	}
 
-				routes = append(routes, child)
-				params[child.Param] = strings.Join(segments, "/")
-				break
+	// Mixed and constrained param segments, i.e. ":name.:ext" or ":id<int>".
+	for _, child := range r.patterns {
+		if child.mixed != nil {
+			values := child.mixed.match(segment)
+			if values == nil {
+				continue
 			}
 
-			params[child.Param] = segment
+			for i, param := range child.mixed.params {
+				params[param] = values[i]
+			}
+			if result, ok := child.resolve(segments[1:], append(routes, child), params, opts); ok {
+				return result, true
+			}
+			for _, param := range child.mixed.params {
+				delete(params, param)
+			}
+			continue
 		}
 
-		routes = append(routes, child)
-		route = child
-		segments = segments[1:]
+		if !child.re.MatchString(segment) {
+			continue
+		}
+
+		params[child.Param] = segment
+		if result, ok := child.resolve(segments[1:], append(routes, child), params, opts); ok {
+			return result, true
+		}
+		delete(params, child.Param)
 	}
 
-	return routes, params, nil
+	// A param segment, i.e. ":param".
+	if child, ok := r.Children[paramSegment]; ok && (segment != "" || !opts.nonEmptyParams) {
+		params[child.Param] = segment
+		if result, ok := child.resolve(segments[1:], append(routes, child), params, opts); ok {
+			return result, true
+		}
+		delete(params, child.Param)
+	}
+
+	// A catch all segment, i.e. "*" or "*filepath".
+	if child, ok := r.Children[catchAllSegment]; ok {
+		rest := strings.Join(segments, "/")
+		if opts.rawPath {
+			if rest, ok = unescape(rest); !ok {
+				return nil, false
+			}
+		}
+
+		params[child.Param] = rest
+		return append(routes, child), true
+	}
+
+	return nil, false
+}
+
+// staticChild returns a static child by its name or nil.
+func (r *Route) staticChild(name string, caseInsensitive bool) *Route {
+	if child, ok := r.Children[name]; ok {
+		return child
+	}
+	if !caseInsensitive {
+		return nil
+	}
+
+	// Pick the smallest key when the children conflict, see checkCaseConflicts.
+	var found *Route
+	var foundKey string
+	for key, child := range r.Children {
+		if child.isStatic() && strings.EqualFold(key, name) && (found == nil || key < foundKey) {
+			found, foundKey = child, key
+		}
+	}
+	return found
+}
+
+// checkCaseConflicts panics when static children differ only in case,
+// they are ambiguous when matched case-insensitively.
+func (r *Route) checkCaseConflicts() {
+	names := make(map[string]string, len(r.Children))
+	for name, child := range r.Children {
+		if child.isStatic() {
+			key := strings.ToLower(name)
+			if other, ok := names[key]; ok {
+				if other > name {
+					other, name = name, other
+				}
+				panic(fmt.Sprintf("router: Static segments %q and %q differ only in case", other, name))
+			}
+			names[key] = name
+		}
+		child.checkCaseConflicts()
+	}
+}
+
+// optionalChild returns an optional param child with handlers when the route has no handlers, or nil.
+func (r *Route) optionalChild() *Route {
+	if len(r.Handlers) > 0 {
+		return nil
+	}
+
+	for _, child := range r.Children {
+		if child.Optional && len(child.Handlers) > 0 {
+			return child
+		}
+	}
+	return nil
+}
+
+// checkOptional panics when a route has another optional child than a given one,
+// the optional child would be ambiguous when the last segment is omitted.
+func (r *Route) checkOptional(child *Route) {
+	for _, other := range r.Children {
+		if other != child && other.Optional {
+			panic(fmt.Sprintf("router: Multiple optional params, previous=%v, current=%v",
+				other.segment(), child.segment()))
+		}
+	}
+}
+
+// isStatic returns true when the route segment has no params.
+func (r *Route) isStatic() bool {
+	return r.Param == "" && r.mixed == nil
+}
+
+// unescape unescapes a path segment when it contains escaped characters.
+func unescape(s string) (string, bool) {
+	if strings.IndexByte(s, '%') < 0 {
+		return s, true
+	}
+
+	s, err := url.PathUnescape(s)
+	return s, err == nil
 }
 
 // makePath gets or creates a path and returns its last segment.
@@ -218,19 +631,32 @@ func (r *Route) makePath(p string) *Route {
 	segments := strings.Split(p, "/")[1:]
 
 	for len(segments) > 0 {
-		name, param := parseNameParam(segments[0])
+		segment := parseSegment(segments[0])
+		if segment.name == catchAllSegment && len(segments) > 1 {
+			panic(fmt.Sprintf("router: Catch-all must be the last segment in pattern %q", p))
+		}
+		if segment.optional && len(segments) > 1 {
+			panic(fmt.Sprintf("router: Optional param must be the last segment in pattern %q", p))
+		}
 
 		// Create a child when absent.
-		child, ok := route.Children[name]
+		child, ok := route.Children[segment.name]
 		if !ok {
-			child = newRoute(name, param)
-			route.Children[name] = child
+			child = newRoute(segment)
+			route.addChild(child)
 		}
 
 		// Check that the param name matches the child param name.
-		if child.Param != param {
+		if !segment.sameParams(child) {
 			panic(fmt.Sprintf("router: Positional params must have the same name, previous=%v, current=%v",
-				child.Param, param))
+				child.segment(), segments[0]))
+		}
+		if segment.optional {
+			if len(route.Handlers) > 0 {
+				panic("router: Optional param conflicts with parent handlers")
+			}
+			child.Optional = true
+			route.checkOptional(child)
 		}
 
 		route = child
@@ -239,17 +665,67 @@ func (r *Route) makePath(p string) *Route {
 	return route
 }
 
-func parseNameParam(segment string) (name string, param string) {
-	name = segment
-	param = ""
+// validMethod returns true when a method is an RFC 7230 token.
+func validMethod(method string) bool {
+	if method == "" {
+		return false
+	}
+
+	for i := 0; i < len(method); i++ {
+		c := method[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Route) addMethodMiddleware(method string, middleware ...Middleware) {
+	if r.MethodMiddle == nil {
+		r.MethodMiddle = make(map[string][]Middleware)
+	}
+	r.MethodMiddle[method] = append(r.MethodMiddle[method], middleware...)
+}
+
+// newOptionsHandler returns a handler which responds to OPTIONS requests with an Allow header.
+func newOptionsHandler(allow []string) Handler {
+	value := strings.Join(allow, ", ")
+
+	return func(ctx context.Context, req *Req, resp *Resp) error {
+		resp.Header().Set("Allow", value)
+		resp.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+}
+
+// addChild adds a child with its name and param already set.
+func (r *Route) addChild(child *Route) {
+	r.Children[child.Name] = child
 
 	switch {
-	case strings.HasPrefix(name, ":"):
-		param = name[1:]
-		name = ":"
-	case name == catchAllSegment:
-		param = catchAllParam
+	case child.mixed != nil:
+		i := 0
+		for i < len(r.patterns) && r.patterns[i].mixed != nil {
+			i++
+		}
+		r.patterns = append(r.patterns[:i], append([]*Route{child}, r.patterns[i:]...)...)
+
+	case child.re != nil:
+		r.patterns = append(r.patterns, child)
 	}
+}
 
-	return
+func (r *Route) setSegment(segment patternSegment) {
+	r.Param = segment.param
+	r.Constraint = segment.constraint
+	r.Optional = r.Optional || segment.optional
+	r.mixed = segment.mixed
+	r.re = nil
+
+	if segment.constraint != "" {
+		r.re = compileConstraint(segment.constraint)
+	}
 }
@@ -3,6 +3,7 @@ package httpd
 import (
 	"context"
 	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -50,6 +51,23 @@ func TestRoute_Handler__should_add_handlers(t *testing.T) {
 	assert.Contains(t, r.Children["hello"].Children["goodbye"].Handlers, ALL)
 }
 
+func TestRoute_Methods__should_add_handler_for_methods(t *testing.T) {
+	r := NewRoute()
+	r.Methods([]string{GET, "patch", "PROPFIND"}, "/hello", dummyHandler)
+
+	assert.Contains(t, r.Children["hello"].Handlers, GET)
+	assert.Contains(t, r.Children["hello"].Handlers, PATCH)
+	assert.Contains(t, r.Children["hello"].Handlers, "PROPFIND")
+}
+
+func TestRoute_Handler__should_reject_invalid_methods(t *testing.T) {
+	r := NewRoute()
+
+	assert.Panics(t, func() { r.Handler("GET POST", "/", dummyHandler) })
+	assert.Panics(t, func() { r.Handler("GET\n", "/", dummyHandler) })
+	assert.Panics(t, func() { r.Handler("(GET)", "/", dummyHandler) })
+}
+
 func TestRoute_Resolve__should_resolve_routes(t *testing.T) {
 	r := NewRoute()
 	root0 := r.makePath("")
@@ -105,5 +123,311 @@ func TestRoute_Resolve(t *testing.T) {
 	}
 }
 
+func TestRoute_Resolve__should_resolve_constrained_params(t *testing.T) {
+	r := NewRoute()
+	root := r.makePath("")
+	users := r.makePath("/users")
+	userID := r.makePath("/users/:id<int>")
+	userUID := r.makePath("/users/:uid<uuid>")
+	userSlug := r.makePath("/users/:slug<[a-z0-9-]+>")
+	userIDPosts := r.makePath("/users/:id<int>/posts")
+	userName := r.makePath("/users/:name/profile")
+
+	assert.Equal(t, userID, users.Children[":<int>"])
+	assert.Equal(t, "id", userID.Param)
+	assert.Equal(t, "int", userID.Constraint)
+
+	cases := []struct {
+		Path   string
+		Routes []*Route
+		Params Params
+	}{
+		{"/users/123", []*Route{root, users, userID}, Params{"id": "123"}},
+		{"/users/123/posts", []*Route{root, users, userID, userIDPosts}, Params{"id": "123"}},
+		{"/users/6ba7b810-9dad-11d1-80b4-00c04fd430c8", []*Route{root, users, userUID},
+			Params{"uid": "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}},
+		{"/users/john-smith", []*Route{root, users, userSlug}, Params{"slug": "john-smith"}},
+		{"/users/123/profile", []*Route{root, users, r.Children["users"].Children[":"], userName},
+			Params{"name": "123"}},
+	}
+
+	for _, c := range cases {
+		routes, params, err := r.Resolve(c.Path)
+		assert.Nil(t, err, c.Path)
+		assert.Equal(t, c.Routes, routes, c.Path)
+		assert.Equal(t, c.Params, params, c.Path)
+	}
+
+	_, _, err := r.Resolve("/users/John_Smith/posts")
+	assert.Equal(t, ErrRouteNotFound, err)
+}
+
+func TestRoute_Resolve__should_resolve_mixed_segments(t *testing.T) {
+	r := NewRoute()
+	file := r.makePath("/files/:name.:ext")
+	catchAll := r.makePath("/files/*filepath")
+	version := r.makePath("/v:version<int>/status")
+	range_ := r.makePath("/range/:from-:to")
+	batch := r.makePath(`/v1\:batch`)
+	cancel := r.makePath(`/jobs/:id\:cancel`)
+
+	assert.Equal(t, file, r.Children["files"].Children[":.:"])
+	assert.Equal(t, batch, r.Children["v1:batch"])
+	assert.Equal(t, `:id\:cancel`, cancel.segment())
+	assert.Equal(t, "filepath", catchAll.Param)
+
+	cases := []struct {
+		Path   string
+		Route  *Route
+		Params Params
+	}{
+		{"/files/a.txt", file, Params{"name": "a", "ext": "txt"}},
+		{"/files/a.tar.gz", file, Params{"name": "a.tar", "ext": "gz"}},
+		{"/files/a", catchAll, Params{"filepath": "a"}},
+		{"/files/a/b.txt", catchAll, Params{"filepath": "a/b.txt"}},
+		{"/v2/status", version, Params{"version": "2"}},
+		{"/range/1-10", range_, Params{"from": "1", "to": "10"}},
+		{"/v1:batch", batch, Params{}},
+		{"/jobs/7:cancel", cancel, Params{"id": "7"}},
+	}
+
+	for _, c := range cases {
+		routes, params, err := r.Resolve(c.Path)
+		assert.Nil(t, err, c.Path)
+		assert.Equal(t, c.Route, routes[len(routes)-1], c.Path)
+		assert.Equal(t, c.Params, params, c.Path)
+	}
+
+	_, _, err := r.Resolve("/vx/status")
+	assert.Equal(t, ErrRouteNotFound, err)
+}
+
+func TestRoute_Resolve__should_prefer_static_mixed_constrained_param_catch_all(t *testing.T) {
+	r := NewRoute()
+	static := r.makePath("/a/new")
+	param := r.makePath("/a/:name")
+	constrained := r.makePath("/a/:id<int>")
+	mixed := r.makePath("/a/:id<int>.json")
+	catchAll := r.makePath("/a/*")
+
+	cases := []struct {
+		Path  string
+		Route *Route
+	}{
+		{"/a/new", static},
+		{"/a/1.json", mixed},
+		{"/a/1", constrained},
+		{"/a/b", param},
+		{"/a/b/c", catchAll},
+		{"/a/", param},
+	}
+
+	for _, c := range cases {
+		routes, _, err := r.Resolve(c.Path)
+		assert.Nil(t, err, c.Path)
+		assert.Equal(t, c.Route, routes[len(routes)-1], c.Path)
+	}
+
+	routes, _, err := r.resolvePath("/a/", matchOptions{nonEmptyParams: true})
+	assert.Nil(t, err)
+	assert.Equal(t, catchAll, routes[len(routes)-1])
+}
+
+func TestRoute_Match__should_match_optional_params(t *testing.T) {
+	r := NewRoute()
+	route := r.GET("/posts/:page?", dummyHandler)
+	assert.True(t, route.Optional)
+
+	_, h, params, err := r.Match(GET, "/posts")
+	assert.Nil(t, err)
+	assert.NotNil(t, h)
+	assert.Equal(t, Params{}, params)
+
+	_, h, params, err = r.Match(GET, "/posts/2")
+	assert.Nil(t, err)
+	assert.NotNil(t, h)
+	assert.Equal(t, Params{"page": "2"}, params)
+
+	_, _, _, err = r.Match(GET, "/posts/2/3")
+	assert.Equal(t, ErrRouteNotFound, err)
+}
+
+func TestRoute_Handler__should_detect_conflicts(t *testing.T) {
+	cases := []struct {
+		Patterns []string
+		Panic    string
+	}{
+		{[]string{"/files/*/name"}, `router: Catch-all must be the last segment in pattern "/files/*/name"`},
+		{[]string{"/files/*path<int>"}, `router: Catch-all params cannot have constraints in segment "*path<int>"`},
+		{[]string{"/posts/:page?/comments"}, `router: Optional param must be the last segment in pattern "/posts/:page?/comments"`},
+		{[]string{"/posts/new?"}, `router: Only params can be optional in segment "new?"`},
+		{[]string{"/files/:name.:ext?"}, `router: Only whole segment params can be optional in segment ":name.:ext?"`},
+		{[]string{"/files/:name:ext"}, `router: Params must be separated by literals in segment ":name:ext"`},
+		{[]string{"/files/:name.:name"}, `router: Duplicate param "name" in segment ":name.:name"`},
+		{[]string{"/files/*", "/files/*filepath"},
+			`router: Positional params must have the same name, previous=*, current=*filepath`},
+		{[]string{"/files/:name.:ext", "/files/:base.:ext"},
+			`router: Positional params must have the same name, previous=:name.:ext, current=:base.:ext`},
+		{[]string{"/posts", "/posts/:page?"}, `router: Optional param conflicts with parent handlers`},
+		{[]string{"/posts/:page?", "/posts"}, `router: Handlers conflict with an optional param child`},
+		{[]string{"/posts/:page?", "/posts/:id<int>?"},
+			`router: Multiple optional params, previous=:page?, current=:id<int>?`},
+	}
+
+	for _, c := range cases {
+		r := NewRoute()
+		assert.PanicsWithValue(t, c.Panic, func() {
+			for _, p := range c.Patterns {
+				r.GET(p, dummyHandler)
+			}
+		}, c.Patterns)
+	}
+}
+
+func TestRoute_URL(t *testing.T) {
+	r := NewRoute()
+	r.GET("/", dummyHandler).Named("index")
+	r.GET("/users/:id", dummyHandler).Named("user")
+	r.GET("/users/:id/files/*", dummyHandler).Named("files")
+	r.GET("/assets/*filepath", dummyHandler).Named("assets")
+	r.GET("/docs/:name.:ext", dummyHandler).Named("doc")
+	r.GET("/posts/:page?", dummyHandler).Named("posts")
+	r.GET(`/v1\:batch`, dummyHandler).Named("batch")
+
+	cases := []struct {
+		Name   string
+		Params Params
+		URL    string
+	}{
+		{"index", nil, "/"},
+		{"user", Params{"id": "123"}, "/users/123"},
+		{"user", Params{"id": "a b/c"}, "/users/a%20b%2Fc"},
+		{"files", Params{"id": "1", "path": "docs/a b.txt"}, "/users/1/files/docs/a%20b.txt"},
+		{"assets", Params{"filepath": "css/main.css"}, "/assets/css/main.css"},
+		{"doc", Params{"name": "read me", "ext": "md"}, "/docs/read%20me.md"},
+		{"posts", nil, "/posts"},
+		{"posts", Params{"page": "2"}, "/posts/2"},
+		{"batch", nil, "/v1:batch"},
+	}
+
+	for _, c := range cases {
+		url, err := r.URL(c.Name, c.Params)
+		assert.Nil(t, err)
+		assert.Equal(t, c.URL, url)
+	}
+}
+
+func TestRoute_URL__should_return_errors(t *testing.T) {
+	r := NewRoute()
+	r.GET("/users/:id", dummyHandler).Named("user")
+	r.GET("/admins/:id", dummyHandler).Named("duplicate")
+	r.GET("/guests/:id", dummyHandler).Named("duplicate")
+
+	_, err := r.URL("unknown", nil)
+	assert.Equal(t, ErrRouteNameNotFound, err)
+
+	_, err = r.URL("user", Params{})
+	assert.EqualError(t, err, `router: Missing param "id"`)
+
+	r.GET("/posts/:id<int>", dummyHandler).Named("post")
+	_, err = r.URL("post", Params{"id": "abc"})
+	assert.EqualError(t, err, `router: Param "id" does not match constraint "int"`)
+
+	r.GET("/docs/:name.:ext", dummyHandler).Named("doc")
+	_, err = r.URL("doc", Params{"name": "a", "ext": "tar.gz"})
+	assert.EqualError(t, err, `router: Params do not match segment ":name.:ext"`)
+
+	_, err = r.URL("duplicate", Params{"id": "1"})
+	assert.EqualError(t, err, `router: Duplicate route name "duplicate"`)
+}
+
+func TestRoute_Match__should_order_middleware_by_scope(t *testing.T) {
+	trace := []string{}
+	record := func(name string) Middleware {
+		return func(ctx context.Context, req *Req, resp *Resp, next Handler) error {
+			trace = append(trace, name)
+			return next(ctx, req, resp)
+		}
+	}
+
+	r := NewRoute()
+	r.GET("/admin", dummyHandler)
+	r.POST("/admin", dummyHandler1)
+	r.GET("/admin/users", dummyHandler)
+	r.Middleware("/", record("root"))
+	r.Middleware("/admin", record("admin"))
+	r.EndpointMiddleware("/admin", record("endpoint"))
+	r.MethodMiddleware(POST, "/admin", record("post0"))
+	r.MethodMiddleware(POST, "/admin", record("post1"))
+
+	cases := []struct {
+		Method string
+		Path   string
+		Trace  []string
+	}{
+		{GET, "/admin", []string{"root", "admin", "endpoint"}},
+		{POST, "/admin", []string{"root", "admin", "endpoint", "post0", "post1"}},
+		{GET, "/admin/users", []string{"root", "admin"}},
+		{OPTIONS, "/admin", []string{"root", "admin", "endpoint"}},
+	}
+
+	for _, c := range cases {
+		trace = trace[:0]
+		middleware, handler, _, err := r.Match(c.Method, c.Path)
+		assert.Nil(t, err)
+
+		resp := &Resp{ResponseWriter: httptest.NewRecorder()}
+		execute(context.Background(), middleware, handler, nil, resp)
+		assert.Equal(t, c.Trace, trace, c.Method+" "+c.Path)
+	}
+}
+
+func TestRoute_Chain(t *testing.T) {
+	r := NewRoute()
+	r.POST("/admin", dummyHandler)
+	r.MethodMiddleware(POST, "/admin", dummyMiddleware)
+
+	chain, err := r.Chain(POST, "/admin")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{funcName(dummyMiddleware), funcName(dummyHandler)}, chain)
+}
+
+func TestRoute_Routes(t *testing.T) {
+	r := NewRoute()
+	r.GET("/", dummyHandler)
+	r.GET("/users/:id<int>", dummyHandler).Named("user")
+	r.POST("/users/:id<int>", dummyHandler1)
+	r.ALL("/files/*", dummyHandler)
+	r.Middleware("/", dummyMiddleware)
+
+	routes := r.Routes()
+	assert.Equal(t, []RouteInfo{
+		{
+			Pattern:    "/",
+			Methods:    []string{GET},
+			Handlers:   map[string]string{GET: funcName(dummyHandler)},
+			Middleware: []string{funcName(dummyMiddleware)},
+		},
+		{
+			Pattern:    "/files/*",
+			Methods:    []string{"*"},
+			Handlers:   map[string]string{"*": funcName(dummyHandler)},
+			Middleware: []string{funcName(dummyMiddleware)},
+		},
+		{
+			Pattern:    "/users/:id<int>",
+			Name:       "user",
+			Methods:    []string{GET, POST},
+			Handlers:   map[string]string{GET: funcName(dummyHandler), POST: funcName(dummyHandler1)},
+			Middleware: []string{funcName(dummyMiddleware)},
+		},
+	}, routes)
+	assert.Equal(t, "github.com/ivankorobkov/go-blink/httpd.dummyHandler", routes[0].Handlers[GET])
+}
+
 func dummyHandler(context.Context, *Req, *Resp) error  { return nil }
 func dummyHandler1(context.Context, *Req, *Resp) error { return nil }
+
+func dummyMiddleware(ctx context.Context, req *Req, resp *Resp, next Handler) error {
+	return next(ctx, req, resp)
+}
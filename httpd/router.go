@@ -5,7 +5,10 @@ import (
 	"context"
 	"github.com/ivankorobkov/go-blink/logs"
 	"net/http"
+	"net/netip"
+	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type Handler func(ctx context.Context, req *Req, resp *Resp) error
@@ -13,7 +16,14 @@ type Middleware func(ctx context.Context, req *Req, resp *Resp, next Handler) er
 
 type Router struct {
 	log        logs.Log
-	route      *Route
+	policy     PathPolicy
+	errors     ErrorHandlers
+	recovery   RecoveryPolicy
+	body       BodyPolicy
+	proxies    []netip.Prefix    // Trusted proxies, see SetTrustedProxies.
+	forwarded  ProxyHeaderSource // Forwarding headers of trusted proxies, see SetProxyHeaderSource.
+	encoders   []encoder
+	table      atomic.Value // *table
 	streams    map[*SSEStream]struct{}
 	websockets map[*WebSocket]struct{}
 
@@ -21,74 +31,214 @@ type Router struct {
 	close  bool
 	closed chan struct{}
 
+	swapMu sync.Mutex // Serializes table swaps.
+
 	buffers sync.Pool // sync.Pool<bytes.Buffer>
 }
 
 func NewRouter(log logs.Log) *Router {
-	return &Router{
+	r := &Router{
 		log:        log,
-		route:      NewRoute(),
 		streams:    make(map[*SSEStream]struct{}),
 		websockets: make(map[*WebSocket]struct{}),
 		closed:     make(chan struct{}),
+		encoders:   defaultEncoders(),
+	}
+	r.table.Store(newTable())
+	return r
+}
+
+func (r *Router) ALL(p string, h Handler) *Route     { return r.root().ALL(p, h) }
+func (r *Router) HEAD(p string, h Handler) *Route    { return r.root().HEAD(p, h) }
+func (r *Router) GET(p string, h Handler) *Route     { return r.root().GET(p, h) }
+func (r *Router) POST(p string, h Handler) *Route    { return r.root().POST(p, h) }
+func (r *Router) PUT(p string, h Handler) *Route     { return r.root().PUT(p, h) }
+func (r *Router) PATCH(p string, h Handler) *Route   { return r.root().PATCH(p, h) }
+func (r *Router) DELETE(p string, h Handler) *Route  { return r.root().DELETE(p, h) }
+func (r *Router) OPTIONS(p string, h Handler) *Route { return r.root().OPTIONS(p, h) }
+func (r *Router) TRACE(p string, h Handler) *Route   { return r.root().TRACE(p, h) }
+func (r *Router) CONNECT(p string, h Handler) *Route { return r.root().CONNECT(p, h) }
+func (r *Router) Static(p string, root http.Dir)     { r.root().Static(p, root) }
+
+func (r *Router) Add(p string, child *Route)                     { r.root().Add(p, child) }
+func (r *Router) Handler(m string, p string, h Handler) *Route   { return r.root().Handler(m, p, h) }
+func (r *Router) Methods(m []string, p string, h Handler) *Route { return r.root().Methods(m, p, h) }
+func (r *Router) Middleware(p string, m Middleware)              { r.root().Middleware(p, m) }
+func (r *Router) EndpointMiddleware(p string, m Middleware)      { r.root().EndpointMiddleware(p, m) }
+func (r *Router) ErrorHandlers(p string, h ErrorHandlers)        { r.root().ErrorHandlers(p, h) }
+
+func (r *Router) MethodMiddleware(method string, p string, m Middleware) {
+	r.root().MethodMiddleware(method, p, m)
+}
+
+func (r *Router) Group(p string, m ...Middleware) *Group {
+	g := &Group{router: r, route: r.root()}
+	return g.Group(p, m...)
+}
+
+// Freeze compiles the route trees into radix trees with precomposed middleware chains
+// which are used to serve requests. The router panics on further registrations,
+// the routes must not be modified after freezing, use Swap and SwapHost to replace them.
+func (r *Router) Freeze() {
+	r.swapMu.Lock()
+	defer r.swapMu.Unlock()
+
+	t := r.load()
+	if t.frozen() {
+		return
+	}
+	r.table.Store(t.copy().freeze(t, r.pathOptions()))
+}
+
+// Swap compiles a new default route tree and atomically replaces the current one, freezes the router.
+// In-flight requests finish on the previous tree, SSE streams and websockets are not affected.
+// The new tree must not be modified after swapping, build a new tree to apply changes.
+func (r *Router) Swap(route *Route) {
+	if route == nil {
+		panic("router: Nil route")
+	}
+
+	r.swapMu.Lock()
+	defer r.swapMu.Unlock()
+
+	t := r.load()
+	t.checkServed(route)
+	next := t.copy()
+	next.route = route
+	r.table.Store(next.freeze(t, r.pathOptions()))
+}
+
+// SwapHost compiles a new host route tree and atomically adds it or replaces the current one,
+// freezes the router, see Swap and Host.
+func (r *Router) SwapHost(pattern string, route *Route) {
+	if route == nil {
+		panic("router: Nil route")
+	}
+
+	r.swapMu.Lock()
+	defer r.swapMu.Unlock()
+
+	t := r.load()
+	t.checkServed(route)
+	next := t.copy()
+
+	h := parseHostPattern(pattern)
+	if h == nil {
+		next.hosts[strings.ToLower(pattern)] = route
+	} else {
+		h.route = route
+		replaced := false
+		for i, h0 := range next.hostPatterns {
+			if h0.pattern == h.pattern {
+				next.hostPatterns[i] = h
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			next.hostPatterns = append(next.hostPatterns, h)
+		}
+	}
+
+	r.table.Store(next.freeze(t, r.pathOptions()))
+}
+
+// load returns the current route table.
+func (r *Router) load() *table {
+	return r.table.Load().(*table)
+}
+
+// root returns the default route tree for registrations, panics when the router is frozen.
+func (r *Router) root() *Route {
+	t := r.load()
+	t.checkFrozen()
+	return t.route
+}
+
+// Chain returns the function names of the effective middleware chain and the handler for a request,
+// the request is matched the same way as in ServeHTTP.
+func (r *Router) Chain(httpReq *http.Request) ([]string, error) {
+	route, _ := r.load().matchHost(httpReq.Host)
+	middleware, handler, _, _, _, err := r.matchPath(route, route, httpReq.Method, r.requestPath(httpReq))
+	if err != nil {
+		return nil, err
 	}
+	return chainNames(middleware, handler), nil
 }
 
-func (r *Router) ALL(p string, h Handler)        { r.route.ALL(p, h) }
-func (r *Router) HEAD(p string, h Handler)       { r.route.HEAD(p, h) }
-func (r *Router) GET(p string, h Handler)        { r.route.GET(p, h) }
-func (r *Router) POST(p string, h Handler)       { r.route.POST(p, h) }
-func (r *Router) PUT(p string, h Handler)        { r.route.PUT(p, h) }
-func (r *Router) DELETE(p string, h Handler)     { r.route.DELETE(p, h) }
-func (r *Router) Static(p string, root http.Dir) { r.route.Static(p, root) }
+// Walk traverses the default host route tree, see Route.Walk.
+func (r *Router) Walk(fn WalkFunc) error { return r.load().route.Walk(fn) }
+
+// Routes returns descriptions of all routes with handlers in the default tree and then in host trees.
+func (r *Router) Routes() []RouteInfo { return r.load().routes() }
 
-func (r *Router) Add(p string, child *Route)            { r.route.Add(p, child) }
-func (r *Router) Handler(m string, p string, h Handler) { r.route.Handler(m, p, h) }
-func (r *Router) Middleware(p string, m Middleware)     { r.route.Middleware(p, m) }
+// URL builds a path to a named route in the default tree or in host trees, see Route.Named.
+func (r *Router) URL(name string, params Params) (string, error) { return r.load().url(name, params) }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, httpReq *http.Request) {
 	ctx := httpReq.Context()
+	t := r.load()
+	route, hostParams := t.matchHost(httpReq.Host)
+	path := r.requestPath(httpReq)
+
+	req := newReq(r, httpReq, nil)
+	resp := newResp(r, w, httpReq)
+	defer req.cleanup()
 	defer func() {
-		if err := recover(); err != nil {
-			if r.log != nil {
-				r.log.Stack(ctx, err)
-			}
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		if recovered := recover(); recovered != nil {
+			r.recoverPanic(ctx, route, path, req, resp, recovered)
 		}
 	}()
 
-	middleware, handler, params, err := r.route.Match(httpReq.Method, httpReq.URL.Path)
-	if err != nil {
-		r.handleError(ctx, w, httpReq, err)
+	var middleware []Middleware
+	var handler Handler
+	var params Params
+	var redirect bool
+	var err error
+	middleware, handler, params, path, redirect, err = r.matchPath(route, t.matcher(route), httpReq.Method, path)
+	if redirect {
+		r.redirectPath(w, httpReq, path)
 		return
 	}
 
-	req := newReq(r, httpReq, params)
-	resp := newResp(r, w)
-	if err := execute(ctx, middleware, handler, req, resp); err != nil {
-		r.handleError(ctx, w, httpReq, err)
-	}
-}
-
-func (r *Router) handleError(ctx context.Context, w http.ResponseWriter, httpReq *http.Request, err error) {
-	switch err {
-	case ErrRouteNotFound:
-		http.NotFound(w, httpReq)
-
-	case ErrMethodNotAllowed:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// Execute not found and method not allowed handlers with the subtree middleware.
+	if err != nil {
+		if err == ErrMethodNotAllowed {
+			r.setAllow(w, route, path)
+		}
 
-	default:
-		if bad, ok := err.(BadRequestError); ok {
-			http.Error(w, bad.Text, http.StatusBadRequest)
-			return
+		handlers, subtree := r.errorHandlers(route, path)
+		middleware, handler = subtree, handlers.NotFound
+		if err == ErrMethodNotAllowed {
+			handler = handlers.MethodNotAllowed
 		}
+	}
 
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		if r.log != nil {
-			r.log.Error(ctx, "Internal server error", err)
+	if len(hostParams) > 0 && params == nil {
+		params = Params{}
+	}
+	for name, value := range hostParams {
+		if _, ok := params[name]; !ok {
+			params[name] = value
 		}
 	}
+
+	req.Params = params
+	if err := execute(ctx, middleware, handler, req, resp); err != nil {
+		handlers, _ := r.errorHandlers(route, path)
+		handlers.Error(ctx, req, resp, err)
+	}
+}
+
+// setAllow sets the Allow header to the methods of a route matching a path.
+func (r *Router) setAllow(w http.ResponseWriter, route *Route, path string) {
+	routes, _, err := route.resolvePath(path, r.pathOptions())
+	if err != nil {
+		return
+	}
+
+	allow := routes[len(routes)-1].Allow()
+	w.Header().Set("Allow", strings.Join(allow, ", "))
 }
 
 func (r *Router) Close() <-chan struct{} {
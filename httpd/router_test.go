@@ -0,0 +1,314 @@
+package httpd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_ServeHTTP__should_set_allow_header_on_method_not_allowed(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/users", dummyHandler)
+	r.POST("/users", dummyHandler)
+
+	w := serve(r, http.MethodDelete, "/users")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS, POST", w.Header().Get("Allow"))
+}
+
+func TestRouter_ServeHTTP__should_respond_to_options(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/users", dummyHandler)
+	r.ALL("/all", dummyHandler)
+	r.Handler(OPTIONS, "/custom", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.Text("custom")
+	})
+
+	w := serve(r, http.MethodOptions, "/users")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+
+	w = serve(r, http.MethodOptions, "/all")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "", w.Header().Get("Allow"))
+
+	w = serve(r, http.MethodOptions, "/custom")
+	assert.Equal(t, "custom", w.Body.String())
+}
+
+func TestRouter_ServeHTTP__should_fall_back_to_get_for_head(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/hello", func(ctx context.Context, req *Req, resp *Resp) error {
+		resp.Header().Set("X-Hello", "world")
+		return resp.Text("Hello, world")
+	})
+
+	w := serve(r, http.MethodHead, "/hello")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "world", w.Header().Get("X-Hello"))
+	assert.Equal(t, "12", w.Header().Get("Content-Length"))
+	assert.Equal(t, 0, w.Body.Len())
+
+	w = serve(r, http.MethodOptions, "/hello")
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+}
+
+func TestRouter_ServeHTTP__should_route_by_host(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/", textHandler("default"))
+	r.Host("api.example.com").GET("/", textHandler("api"))
+	r.Host("admin.example.com:8080").GET("/", textHandler("admin"))
+	r.Host(":tenantId.Example.com").GET("/users/:id", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.Text(req.Param("tenantId") + " " + req.Param("id"))
+	})
+	r.Host("*.cdn.example.com").GET("/", textHandler("cdn"))
+
+	cases := []struct {
+		Host string
+		Path string
+		Text string
+	}{
+		{"example.com", "/", "default"},
+		{"api.example.com", "/", "api"},
+		{"API.example.com:443", "/", "api"},
+		{"admin.example.com:8080", "/", "admin"},
+		{"admin.example.org:8080", "/", "default"},
+		{"acme.example.com", "/users/1", "acme 1"},
+		{"acme.EXAMPLE.com", "/users/1", "acme 1"},
+		{"a.b.cdn.example.com", "/", "cdn"},
+		{"cdn.example.org", "/", "default"},
+	}
+
+	for _, c := range cases {
+		req := newTestRequest(http.MethodGet, c.Path)
+		req.Host = c.Host
+		w := serveRequest(r, req)
+
+		assert.Equal(t, c.Text, w.Body.String(), c.Host)
+	}
+}
+
+func TestRouter_Host__should_panic_on_invalid_param_name(t *testing.T) {
+	r := NewRouter(nil)
+
+	assert.PanicsWithValue(t, `router: Invalid param name in host pattern ":tenant-id.example.com"`, func() {
+		r.Host(":tenant-id.example.com")
+	})
+}
+
+func TestRouter_ServeHTTP__should_apply_path_policy(t *testing.T) {
+	r := NewRouter(nil)
+	r.SetPathPolicy(PathPolicy{
+		Clean:              true,
+		StripTrailingSlash: true,
+		CaseInsensitive:    true,
+		RawPath:            true,
+	})
+	r.GET("/a/b", textHandler("a/b"))
+	r.GET("/files/:name", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.Text(req.Param("name"))
+	})
+	r.GET("/static/*", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.Text(req.Param("path"))
+	})
+
+	cases := []struct {
+		Path string
+		Text string
+	}{
+		{"/a/b", "a/b"},
+		{"/a//b", "a/b"},
+		{"/a/./b", "a/b"},
+		{"/a/b/", "a/b"},
+		{"/A/B", "a/b"},
+		{"/files/a%2Fb", "a/b"},
+		{"/static/dir/", "dir/"},
+	}
+
+	for _, c := range cases {
+		w := serve(r, http.MethodGet, c.Path)
+		assert.Equal(t, c.Text, w.Body.String(), c.Path)
+	}
+}
+
+func TestRouter_ServeHTTP__should_redirect_to_canonical_path(t *testing.T) {
+	r := NewRouter(nil)
+	r.SetPathPolicy(PathPolicy{
+		Clean:              true,
+		StripTrailingSlash: true,
+		RedirectStatus:     http.StatusPermanentRedirect,
+	})
+	r.GET("/a/b", textHandler("a/b"))
+
+	w := serve(r, http.MethodGet, "/a//b/?q=1")
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+	assert.Equal(t, "/a/b?q=1", w.Header().Get("Location"))
+
+	w = serve(r, http.MethodGet, "/a/c/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(r, http.MethodGet, "/a/b")
+	assert.Equal(t, "a/b", w.Body.String())
+}
+
+func TestRouter_ServeHTTP__should_redirect_to_canonical_case(t *testing.T) {
+	r := NewRouter(nil)
+	r.SetPathPolicy(PathPolicy{
+		CaseInsensitive: true,
+		RedirectStatus:  http.StatusMovedPermanently,
+	})
+	r.GET("/Users/:id/Posts", textHandler("posts"))
+	r.GET("/static/*", textHandler("static"))
+
+	for _, freeze := range []bool{false, true} {
+		if freeze {
+			r.Freeze()
+		}
+
+		w := serve(r, http.MethodGet, "/users/Alice/posts?q=1")
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "/Users/Alice/Posts?q=1", w.Header().Get("Location"))
+
+		w = serve(r, http.MethodGet, "/STATIC/Dir/File")
+		assert.Equal(t, http.StatusMovedPermanently, w.Code)
+		assert.Equal(t, "/static/Dir/File", w.Header().Get("Location"))
+
+		w = serve(r, http.MethodGet, "/Users/Alice/Posts")
+		assert.Equal(t, "posts", w.Body.String())
+	}
+}
+
+func TestRouter_SetPathPolicy__should_panic_on_case_conflicts(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/api/Users", dummyHandler)
+	r.GET("/api/users", dummyHandler)
+
+	assert.PanicsWithValue(t, `router: Static segments "Users" and "users" differ only in case`, func() {
+		r.SetPathPolicy(PathPolicy{CaseInsensitive: true})
+	})
+
+	r = NewRouter(nil)
+	r.SetPathPolicy(PathPolicy{CaseInsensitive: true})
+	r.GET("/api/Users", dummyHandler)
+	r.GET("/api/users", dummyHandler)
+	assert.Panics(t, func() { r.Freeze() })
+}
+
+func TestRouter_ServeHTTP__should_match_empty_params_by_policy(t *testing.T) {
+	for _, policy := range []PathPolicy{{}, {NonEmptyParams: true}} {
+		r := NewRouter(nil)
+		r.SetPathPolicy(policy)
+		r.GET("/users/:id/posts", func(ctx context.Context, req *Req, resp *Resp) error {
+			return resp.Text("id=" + req.Param("id"))
+		})
+
+		for _, freeze := range []bool{false, true} {
+			if freeze {
+				r.Freeze()
+			}
+
+			w := serve(r, http.MethodGet, "/users//posts")
+			if policy.NonEmptyParams {
+				assert.Equal(t, http.StatusNotFound, w.Code)
+			} else {
+				assert.Equal(t, "id=", w.Body.String())
+			}
+		}
+	}
+}
+
+func TestRouter_ServeHTTP__should_use_subtree_error_handlers(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/", dummyHandler)
+	r.GET("/api/users", dummyHandler)
+	r.GET("/api/fail", func(ctx context.Context, req *Req, resp *Resp) error {
+		return errors.New("failure")
+	})
+	r.Middleware("/api", headerMiddleware("X-Api"))
+
+	r.SetErrorHandlers(ErrorHandlers{
+		NotFound: func(ctx context.Context, req *Req, resp *Resp) error {
+			return resp.TextStatus("page not found", http.StatusNotFound)
+		},
+	})
+	r.ErrorHandlers("/api", ErrorHandlers{
+		NotFound: func(ctx context.Context, req *Req, resp *Resp) error {
+			return resp.JSONStatus(map[string]string{"error": "not found"}, http.StatusNotFound)
+		},
+		MethodNotAllowed: func(ctx context.Context, req *Req, resp *Resp) error {
+			return resp.JSONStatus(map[string]string{"error": "method not allowed"}, http.StatusMethodNotAllowed)
+		},
+		Error: func(ctx context.Context, req *Req, resp *Resp, err error) {
+			resp.JSONStatus(map[string]string{"error": err.Error()}, http.StatusInternalServerError)
+		},
+	})
+
+	w := serve(r, http.MethodGet, "/unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "page not found", w.Body.String())
+
+	w = serve(r, http.MethodGet, "/api/unknown/path")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "true", w.Header().Get("X-Api"))
+	assert.JSONEq(t, `{"error": "not found"}`, w.Body.String())
+
+	w = serve(r, http.MethodPost, "/api/users")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+	assert.JSONEq(t, `{"error": "method not allowed"}`, w.Body.String())
+
+	w = serve(r, http.MethodGet, "/api/fail")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.JSONEq(t, `{"error": "failure"}`, w.Body.String())
+}
+
+func TestRouter_ServeHTTP__should_use_panic_handler(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/users/:id", func(ctx context.Context, req *Req, resp *Resp) error {
+		panic("failure")
+	})
+
+	var recovered interface{}
+	var params Params
+	r.SetErrorHandlers(ErrorHandlers{
+		Panic: func(ctx context.Context, req *Req, resp *Resp, p *Panic) {
+			recovered, params = p.Value, req.Params
+			resp.WriteHeader(http.StatusServiceUnavailable)
+		},
+	})
+
+	w := serve(r, http.MethodGet, "/users/1")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "failure", recovered)
+	assert.Equal(t, Params{"id": "1"}, params)
+}
+
+func serve(r *Router, method string, target string) *httptest.ResponseRecorder {
+	return serveHTTP(r, method, target)
+}
+
+func serveHTTP(h http.Handler, method string, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newTestRequest(method, target))
+	return w
+}
+
+func serveRequest(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func newTestRequest(method string, target string) *http.Request {
+	return httptest.NewRequest(method, target, nil)
+}
+
+func textHandler(text string) Handler {
+	return func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.Text(text)
+	}
+}
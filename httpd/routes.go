@@ -0,0 +1,190 @@
+package httpd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes a route with handlers.
+type RouteInfo struct {
+	Host       string            `json:"host,omitempty"` // Host pattern or empty for the default tree.
+	Pattern    string            `json:"pattern"`
+	Name       string            `json:"name,omitempty"`
+	Methods    []string          `json:"methods"`
+	Handlers   map[string]string `json:"handlers"`   // map[method]function name, ALL is "*".
+	Middleware []string          `json:"middleware"` // Subtree middleware from the root and endpoint middleware.
+
+	MethodMiddleware map[string][]string `json:"method_middleware,omitempty"` // map[method]function names.
+}
+
+// WalkFunc is called for each route in a tree with its full pattern and the middleware chain
+// from the root to the route.
+type WalkFunc func(pattern string, route *Route, middleware []Middleware) error
+
+// Walk traverses the route tree in the pattern order and calls a function for each route.
+func (r *Route) Walk(fn WalkFunc) error {
+	return r.walk("", nil, fn)
+}
+
+func (r *Route) walk(pattern string, middleware []Middleware, fn WalkFunc) error {
+	middleware = append(middleware[:len(middleware):len(middleware)], r.Middle...)
+
+	p := pattern
+	if p == "" {
+		p = "/"
+	}
+	if err := fn(p, r, middleware); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(r.Children))
+	for name := range r.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		child := r.Children[name]
+		if err := child.walk(pattern+"/"+child.segment(), middleware, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Routes returns descriptions of all routes with handlers sorted by their patterns.
+func (r *Route) Routes() []RouteInfo {
+	result := []RouteInfo{}
+	r.Walk(func(pattern string, route *Route, middleware []Middleware) error {
+		if len(route.Handlers) == 0 {
+			return nil
+		}
+
+		info := RouteInfo{
+			Pattern:    pattern,
+			Name:       route.Alias,
+			Methods:    []string{},
+			Handlers:   make(map[string]string, len(route.Handlers)),
+			Middleware: make([]string, 0, len(middleware)),
+		}
+		for method, h := range route.Handlers {
+			if method == ALL {
+				method = "*"
+			}
+			info.Methods = append(info.Methods, method)
+			info.Handlers[method] = funcName(h)
+		}
+		for _, m := range middleware {
+			info.Middleware = append(info.Middleware, funcName(m))
+		}
+		for _, m := range route.EndpointMiddle {
+			info.Middleware = append(info.Middleware, funcName(m))
+		}
+		for method, middleware := range route.MethodMiddle {
+			if len(middleware) == 0 {
+				continue
+			}
+			if method == ALL {
+				method = "*"
+			}
+			if info.MethodMiddleware == nil {
+				info.MethodMiddleware = make(map[string][]string)
+			}
+			info.MethodMiddleware[method] = chainNames(middleware, nil)
+		}
+		sort.Strings(info.Methods)
+
+		result = append(result, info)
+		return nil
+	})
+	return result
+}
+
+func appendHostRoutes(result []RouteInfo, host string, route *Route) []RouteInfo {
+	for _, info := range route.Routes() {
+		info.Host = host
+		result = append(result, info)
+	}
+	return result
+}
+
+// segment returns the route pattern segment, i.e. "hello", ":id<int>", ":page?", ":name.:ext" or "*filepath".
+func (r *Route) segment() string {
+	switch {
+	case r.Name == catchAllSegment && r.Param == catchAllParam:
+		return catchAllSegment
+	case r.Name == catchAllSegment:
+		return catchAllSegment + r.Param
+	case r.mixed != nil:
+		return r.mixed.pattern
+	case r.Param == "":
+		return escapeColons(r.Name)
+	}
+
+	segment := paramSegment + r.Param
+	if r.Constraint != "" {
+		segment += "<" + r.Constraint + ">"
+	}
+	if r.Optional {
+		segment += "?"
+	}
+	return segment
+}
+
+// NewRoutesHandler returns a handler which renders the router routes as JSON,
+// or as text when the format query param is "text".
+func NewRoutesHandler() Handler {
+	return func(ctx context.Context, req *Req, resp *Resp) error {
+		routes := req.Router.Routes()
+		if req.URL.Query().Get("format") != "text" {
+			return resp.JSON(routes)
+		}
+
+		buf := &bytes.Buffer{}
+		w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+		for _, route := range routes {
+			for _, method := range route.Methods {
+				middleware := append(route.Middleware[:len(route.Middleware):len(route.Middleware)],
+					route.MethodMiddleware[method]...)
+
+				fmt.Fprintf(w, "%v\t%v%v\t%v\t%d\t%v\n", method, route.Host, route.Pattern, route.Handlers[method],
+					len(middleware), strings.Join(middleware, ", "))
+			}
+		}
+		w.Flush()
+		return resp.TextStatus(buf.String(), http.StatusOK)
+	}
+}
+
+// chainNames returns the function names of middleware and an optional handler.
+func chainNames(middleware []Middleware, handler Handler) []string {
+	result := make([]string, 0, len(middleware)+1)
+	for _, m := range middleware {
+		result = append(result, funcName(m))
+	}
+	if handler != nil {
+		result = append(result, funcName(handler))
+	}
+	return result
+}
+
+// funcName returns a function name or an empty string.
+func funcName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+	return f.Name()
+}
@@ -0,0 +1,150 @@
+package httpd
+
+import (
+	"fmt"
+	"sort"
+)
+
+// table contains the router route trees. It is mutated in place only by registrations
+// before the router is frozen, frozen tables are immutable and replaced atomically.
+type table struct {
+	route        *Route            // Default host route tree.
+	hosts        map[string]*Route // Static host route trees, see Router.Host.
+	hostPatterns []*hostPattern
+	compiled     map[*Route]*tree // Compiled route trees, set in frozen tables.
+}
+
+func newTable() *table {
+	return &table{
+		route: NewRoute(),
+		hosts: make(map[string]*Route),
+	}
+}
+
+func (t *table) frozen() bool {
+	return t.compiled != nil
+}
+
+func (t *table) checkFrozen() {
+	if t.frozen() {
+		panic("router: Router is frozen")
+	}
+}
+
+// copy returns a shallow copy of the table with copied hosts.
+func (t *table) copy() *table {
+	hosts := make(map[string]*Route, len(t.hosts))
+	for host, route := range t.hosts {
+		hosts[host] = route
+	}
+
+	return &table{
+		route:        t.route,
+		hosts:        hosts,
+		hostPatterns: append([]*hostPattern{}, t.hostPatterns...),
+	}
+}
+
+// freeze compiles the table trees, reuses already compiled trees from a previous table.
+func (t *table) freeze(prev *table, opts matchOptions) *table {
+	compiled := make(map[*Route]*tree, len(t.hosts)+len(t.hostPatterns)+1)
+	compile := func(route *Route) {
+		if tree, ok := prev.compiled[route]; ok {
+			compiled[route] = tree
+			return
+		}
+		compiled[route] = compileTree(route, opts)
+	}
+
+	names := make(map[string]bool)
+	for _, route := range t.trees() {
+		if opts.caseInsensitive {
+			route.checkCaseConflicts()
+		}
+		compile(route)
+		route.checkAliases(names)
+	}
+
+	t.compiled = compiled
+	return t
+}
+
+// checkServed panics when a route tree is already served by a frozen table,
+// served trees are read by requests and must not be modified and swapped again.
+func (t *table) checkServed(route *Route) {
+	if _, ok := t.compiled[route]; ok {
+		panic("router: Route is already served, swap a new route")
+	}
+}
+
+// matcher returns a compiled route tree or the route tree itself.
+func (t *table) matcher(route *Route) matcher {
+	if tree, ok := t.compiled[route]; ok {
+		return tree
+	}
+	return route
+}
+
+// routes returns descriptions of all routes in the default tree and then in host trees.
+func (t *table) routes() []RouteInfo {
+	result := t.route.Routes()
+
+	hosts := make([]string, 0, len(t.hosts))
+	for host := range t.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		result = appendHostRoutes(result, host, t.hosts[host])
+	}
+	for _, h := range t.hostPatterns {
+		result = appendHostRoutes(result, h.pattern, h.route)
+	}
+	return result
+}
+
+// url builds a path to a named route in the default tree or in host trees,
+// returns an error when the name is used in multiple trees.
+func (t *table) url(name string, params Params) (string, error) {
+	result, resultErr := "", ErrRouteNameNotFound
+	for _, route := range t.trees() {
+		url, err := route.URL(name, params)
+		if err == ErrRouteNameNotFound {
+			continue
+		}
+		if resultErr != ErrRouteNameNotFound {
+			return "", fmt.Errorf("router: Duplicate route name %q", name)
+		}
+		result, resultErr = url, err
+	}
+	return result, resultErr
+}
+
+// trees returns the distinct route trees in a deterministic order: the default tree,
+// static host trees sorted by host and host pattern trees in the order they were added.
+func (t *table) trees() []*Route {
+	hosts := make([]string, 0, len(t.hosts))
+	for host := range t.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	seen := make(map[*Route]bool)
+	result := make([]*Route, 0, len(hosts)+len(t.hostPatterns)+1)
+	add := func(route *Route) {
+		if !seen[route] {
+			seen[route] = true
+			result = append(result, route)
+		}
+	}
+
+	add(t.route)
+	for _, host := range hosts {
+		add(t.hosts[host])
+	}
+	for _, h := range t.hostPatterns {
+		add(h.route)
+	}
+	return result
+}
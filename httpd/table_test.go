@@ -0,0 +1,127 @@
+package httpd
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_Swap(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/", textHandler("v0"))
+
+	route := NewRoute()
+	route.GET("/", textHandler("v1"))
+	route.GET("/new", textHandler("new"))
+	r.Swap(route)
+
+	assert.Equal(t, "v1", serve(r, http.MethodGet, "/").Body.String())
+	assert.Equal(t, "new", serve(r, http.MethodGet, "/new").Body.String())
+	assert.Panics(t, func() { r.GET("/other", dummyHandler) })
+}
+
+func TestRouter_Swap__should_serve_a_new_tree(t *testing.T) {
+	r := NewRouter(nil)
+	route := NewRoute()
+	route.GET("/", textHandler("v1"))
+	r.Swap(route)
+
+	route = NewRoute()
+	route.GET("/", textHandler("v2"))
+	route.GET("/new", textHandler("new"))
+	r.Swap(route)
+	assert.Equal(t, "v2", serve(r, http.MethodGet, "/").Body.String())
+	assert.Equal(t, "new", serve(r, http.MethodGet, "/new").Body.String())
+
+	host := NewRoute()
+	host.GET("/", textHandler("api1"))
+	r.SwapHost("api.example.com", host)
+
+	host = NewRoute()
+	host.GET("/", textHandler("api2"))
+	r.SwapHost("api.example.com", host)
+
+	req := newTestRequest(http.MethodGet, "/")
+	req.Host = "api.example.com"
+	assert.Equal(t, "api2", serveRequest(r, req).Body.String())
+}
+
+func TestRouter_Swap__should_panic_on_served_route(t *testing.T) {
+	r := NewRouter(nil)
+	route := NewRoute()
+	route.GET("/", textHandler("v1"))
+	r.Swap(route)
+
+	msg := "router: Route is already served, swap a new route"
+	assert.PanicsWithValue(t, msg, func() { r.Swap(route) })
+	assert.PanicsWithValue(t, msg, func() { r.SwapHost("api.example.com", route) })
+}
+
+func TestRouter_SwapHost(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/", textHandler("default"))
+
+	route := NewRoute()
+	route.GET("/", textHandler("tenant"))
+	r.SwapHost(":tenant.example.com", route)
+
+	req := newTestRequest(http.MethodGet, "/")
+	req.Host = "acme.example.com"
+	assert.Equal(t, "tenant", serveRequest(r, req).Body.String())
+	assert.Equal(t, "default", serve(r, http.MethodGet, "/").Body.String())
+}
+
+func TestRouter_Swap__should_be_safe_while_serving(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/", textHandler("v0"))
+	r.Freeze()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				w := serve(r, http.MethodGet, "/")
+				assert.Equal(t, http.StatusOK, w.Code)
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		route := NewRoute()
+		route.GET("/", textHandler("v1"))
+		r.Swap(route)
+	}
+	wg.Wait()
+}
+
+func TestRouter_Freeze__should_panic_on_duplicate_route_names(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/admins/:id", dummyHandler).Named("user")
+	r.GET("/guests/:id", dummyHandler).Named("user")
+	assert.PanicsWithValue(t, `router: Duplicate route name "user"`, func() { r.Freeze() })
+
+	route := NewRoute()
+	route.GET("/a", dummyHandler).Named("a")
+	route.GET("/b", dummyHandler).Named("a")
+	assert.Panics(t, func() { NewRouter(nil).Swap(route) })
+}
+
+func TestRouter_URL__should_reject_duplicate_names_across_hosts(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/", dummyHandler).Named("index")
+	r.Host("api.example.com").GET("/users/:id", dummyHandler).Named("user")
+	r.Host("admin.example.com").GET("/users/:id", dummyHandler).Named("admin")
+
+	url, err := r.URL("user", Params{"id": "1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/users/1", url)
+
+	r.Host(":tenant.example.com").GET("/admins/:id", dummyHandler).Named("admin")
+	_, err = r.URL("admin", Params{"id": "1"})
+	assert.EqualError(t, err, `router: Duplicate route name "admin"`)
+	assert.PanicsWithValue(t, `router: Duplicate route name "admin"`, func() { r.Freeze() })
+}
@@ -0,0 +1,337 @@
+package httpd
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// tree is a compiled route tree, it is a compressed radix tree of static paths
+// with param and catch-all children at segment boundaries and precomposed middleware chains.
+// Matching does not allocate for static routes, params are collected in a pooled slice.
+type tree struct {
+	route *Route
+	root  *node
+	opts  matchOptions
+}
+
+// matcher matches a method and a path, it is implemented by Route and tree.
+type matcher interface {
+	match(method string, path string, opts matchOptions) ([]Middleware, Handler, Params, error)
+}
+
+type node struct {
+	prefix   string
+	indices  []byte  // First bytes of static children.
+	children []*node // Static children.
+	route    *Route  // Set when the node ends a route path.
+	endpoint *endpoint
+	dynamic  []*dynamic // Param and catch-all children in the resolution order.
+}
+
+type dynamic struct {
+	param    string
+	re       *regexp.Regexp
+	mixed    *segmentPattern
+	catchAll bool
+	node     *node // Compiled child subtree, its static paths start with a slash.
+}
+
+// endpoint contains precomposed method handlers of a route.
+type endpoint struct {
+	handlers map[string]Handler // map[method]Handler
+}
+
+type param struct {
+	name  string
+	value string
+}
+
+var paramsPool = sync.Pool{
+	New: func() interface{} {
+		ps := make([]param, 0, 8)
+		return &ps
+	},
+}
+
+// compileTree compiles a route tree, the tree must not be modified afterwards.
+func compileTree(route *Route, opts matchOptions) *tree {
+	return &tree{
+		route: route,
+		root:  compileNode(route, nil),
+		opts:  opts,
+	}
+}
+
+// compileNode compiles a route and its static descendants into a radix tree.
+func compileNode(route *Route, middleware []Middleware) *node {
+	root := &node{}
+	root.insertRoute("", route, middleware)
+	return root
+}
+
+func (n *node) insertRoute(key string, route *Route, middleware []Middleware) {
+	middleware = append(middleware[:len(middleware):len(middleware)], route.Middle...)
+
+	leaf := n.insert(key)
+	leaf.route = route
+	leaf.endpoint = newEndpoint(route, middleware)
+	if optional := route.optionalChild(); optional != nil {
+		leaf.endpoint = newEndpoint(optional, append(middleware[:len(middleware):len(middleware)], optional.Middle...))
+	}
+
+	names := make([]string, 0, len(route.Children))
+	for name, child := range route.Children {
+		if child.isStatic() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		n.insertRoute(key+"/"+name, route.Children[name], middleware)
+	}
+
+	children := append([]*Route{}, route.patterns...)
+	if child, ok := route.Children[paramSegment]; ok {
+		children = append(children, child)
+	}
+	if child, ok := route.Children[catchAllSegment]; ok {
+		children = append(children, child)
+	}
+
+	for _, child := range children {
+		leaf.dynamic = append(leaf.dynamic, &dynamic{
+			param:    child.Param,
+			re:       child.re,
+			mixed:    child.mixed,
+			catchAll: child.Name == catchAllSegment,
+			node:     compileNode(child, middleware),
+		})
+	}
+}
+
+// insert returns a node for a static key, splits nodes and creates absent ones.
+func (n *node) insert(key string) *node {
+	for {
+		i := 0
+		for i < len(key) && i < len(n.prefix) && key[i] == n.prefix[i] {
+			i++
+		}
+
+		if i < len(n.prefix) {
+			child := &node{}
+			*child = *n
+			child.prefix = n.prefix[i:]
+
+			*n = node{
+				prefix:   n.prefix[:i],
+				indices:  []byte{child.prefix[0]},
+				children: []*node{child},
+			}
+		}
+
+		key = key[i:]
+		if key == "" {
+			return n
+		}
+
+		found := false
+		for j, index := range n.indices {
+			if index == key[0] {
+				n = n.children[j]
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		child := &node{prefix: key}
+		n.indices = append(n.indices, key[0])
+		n.children = append(n.children, child)
+		return child
+	}
+}
+
+func newEndpoint(route *Route, middleware []Middleware) *endpoint {
+	if len(route.Handlers) == 0 {
+		return nil
+	}
+
+	middleware = append(middleware[:len(middleware):len(middleware)], route.EndpointMiddle...)
+	e := &endpoint{handlers: make(map[string]Handler, len(route.Handlers)+2)}
+	for method, h := range route.Handlers {
+		chain := append(middleware[:len(middleware):len(middleware)], route.MethodMiddle[method]...)
+		e.handlers[method] = compose(chain, h)
+	}
+
+	if _, ok := route.Handlers[ALL]; ok {
+		return e
+	}
+	if _, ok := route.Handlers[HEAD]; !ok {
+		if h, ok := e.handlers[GET]; ok {
+			e.handlers[HEAD] = h
+		}
+	}
+	if _, ok := route.Handlers[OPTIONS]; !ok {
+		chain := append(middleware[:len(middleware):len(middleware)], route.MethodMiddle[OPTIONS]...)
+		e.handlers[OPTIONS] = compose(chain, newOptionsHandler(route.Allow()))
+	}
+	return e
+}
+
+// compose returns a handler which executes middleware and a handler.
+func compose(middleware []Middleware, handler Handler) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		m, next := middleware[i], handler
+		handler = func(ctx context.Context, req *Req, resp *Resp) error {
+			return m(ctx, req, resp, next)
+		}
+	}
+	return handler
+}
+
+// match returns a composed handler, the middleware is always nil.
+func (t *tree) match(method string, path string, opts matchOptions) ([]Middleware, Handler, Params, error) {
+	// Escaped paths require unescaping segments, fall back to the route tree.
+	if opts.rawPath && strings.IndexByte(path, '%') >= 0 {
+		return t.route.match(method, path, opts)
+	}
+
+	ps := paramsPool.Get().(*[]param)
+	defer func() {
+		*ps = (*ps)[:0]
+		paramsPool.Put(ps)
+	}()
+
+	var n *node
+	switch {
+	case path == "" || path == "/":
+		n = t.root
+	case path[0] != '/':
+		n = t.lookup(t.root, "/"+path, ps)
+	default:
+		n = t.lookup(t.root, path, ps)
+	}
+
+	if n == nil {
+		return nil, nil, nil, ErrRouteNotFound
+	}
+	if n.endpoint == nil {
+		return nil, nil, nil, ErrMethodNotAllowed
+	}
+
+	handler, ok := n.endpoint.handlers[method]
+	if !ok {
+		handler, ok = n.endpoint.handlers[ALL]
+		if !ok {
+			return nil, nil, nil, ErrMethodNotAllowed
+		}
+	}
+
+	var params Params
+	if len(*ps) > 0 {
+		params = make(Params, len(*ps))
+		for _, p := range *ps {
+			params[p.name] = p.value
+		}
+	}
+	return nil, handler, params, nil
+}
+
+// lookup returns a node which ends a path or nil, appends params to a stack.
+func (t *tree) lookup(n *node, path string, ps *[]param) *node {
+	if !t.hasPrefix(path, n.prefix) {
+		return nil
+	}
+
+	path = path[len(n.prefix):]
+	if path == "" {
+		if n.route != nil {
+			return n
+		}
+		return nil
+	}
+
+	// Static children.
+	c := path[0]
+	for i, index := range n.indices {
+		if index == c {
+			if found := t.lookup(n.children[i], path, ps); found != nil {
+				return found
+			}
+			break
+		}
+	}
+	if t.opts.caseInsensitive {
+		lower := toLower(c)
+		for i, index := range n.indices {
+			if index != c && toLower(index) == lower {
+				if found := t.lookup(n.children[i], path, ps); found != nil {
+					return found
+				}
+			}
+		}
+	}
+
+	// Dynamic children.
+	if n.route == nil || c != '/' {
+		return nil
+	}
+
+	path = path[1:]
+	segment, rest := path, ""
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		segment, rest = path[:i], path[i:]
+	}
+
+	for _, d := range n.dynamic {
+		mark := len(*ps)
+		switch {
+		case d.catchAll:
+			*ps = append(*ps, param{d.param, path})
+			return d.node
+		case d.mixed != nil:
+			values := d.mixed.match(segment)
+			if values == nil {
+				continue
+			}
+			for i, name := range d.mixed.params {
+				*ps = append(*ps, param{name, values[i]})
+			}
+		case d.re != nil && !d.re.MatchString(segment):
+			continue
+		case d.re == nil && segment == "" && t.opts.nonEmptyParams:
+			continue
+		default:
+			*ps = append(*ps, param{d.param, segment})
+		}
+
+		if found := t.lookup(d.node, rest, ps); found != nil {
+			return found
+		}
+		*ps = (*ps)[:mark]
+	}
+	return nil
+}
+
+func (t *tree) hasPrefix(s string, prefix string) bool {
+	if len(s) < len(prefix) {
+		return false
+	}
+	if t.opts.caseInsensitive {
+		return strings.EqualFold(s[:len(prefix)], prefix)
+	}
+	return s[:len(prefix)] == prefix
+}
+
+func toLower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
@@ -0,0 +1,143 @@
+package httpd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree_match__should_match_as_route(t *testing.T) {
+	r := testRoute()
+	tree := compileTree(r, matchOptions{})
+
+	paths := []string{
+		"", "/", "/hello", "/hello/", "/hello/world", "/hello/worlds", "/hello/wor",
+		"/users", "/users/123", "/users/abc", "/users/123/posts", "/users/abc/posts",
+		"/users/6ba7b810-9dad-11d1-80b4-00c04fd430c8", "/users/new", "/users/new/posts",
+		"/files", "/files/", "/files/a/b/c", "/unknown", "/users//posts",
+		"/files/a.txt", "/files/a.tar.gz", "/files/a.", "/assets/css/main.css", "/v1/status", "/vx/status",
+		"/posts", "/posts/", "/posts/2",
+	}
+
+	for _, path := range paths {
+		for _, method := range []string{GET, HEAD, POST, OPTIONS, DELETE} {
+			_, h0, params0, err0 := r.Match(method, path)
+			_, h1, params1, err1 := tree.match(method, path, matchOptions{})
+
+			name := method + " " + path
+			assert.Equal(t, err0, err1, name)
+			assert.Equal(t, h0 == nil, h1 == nil, name)
+			if len(params0) == 0 {
+				assert.Empty(t, params1, name)
+			} else {
+				assert.Equal(t, params0, params1, name)
+			}
+		}
+	}
+}
+
+func TestTree_match__should_compose_middleware(t *testing.T) {
+	r := testRoute()
+	tree := compileTree(r, matchOptions{})
+
+	_, handler, _, err := tree.match(POST, "/users/123", matchOptions{})
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	handler(context.Background(), &Req{}, &Resp{ResponseWriter: w})
+	assert.Equal(t, "root,users,post,handler", w.Body.String())
+}
+
+func TestTree_match__should_not_allocate_for_static_routes(t *testing.T) {
+	tree := compileTree(testRoute(), matchOptions{})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		tree.match(GET, "/hello/world", matchOptions{})
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestRouter_Freeze(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/users/:id", func(ctx context.Context, req *Req, resp *Resp) error {
+		return resp.Text(req.Param("id"))
+	})
+	r.Freeze()
+
+	w := serve(r, http.MethodGet, "/users/123")
+	assert.Equal(t, "123", w.Body.String())
+	assert.Panics(t, func() { r.GET("/", dummyHandler) })
+}
+
+func BenchmarkRoute_Match__static(b *testing.B) {
+	benchmarkMatch(b, testRoute(), GET, "/hello/world")
+}
+
+func BenchmarkTree_match__static(b *testing.B) {
+	benchmarkMatch(b, compileTree(testRoute(), matchOptions{}), GET, "/hello/world")
+}
+
+func BenchmarkRoute_Match__params(b *testing.B) {
+	benchmarkMatch(b, testRoute(), POST, "/users/123/posts")
+}
+
+func BenchmarkTree_match__params(b *testing.B) {
+	benchmarkMatch(b, compileTree(testRoute(), matchOptions{}), POST, "/users/123/posts")
+}
+
+func benchmarkMatch(b *testing.B, m matcher, method string, path string) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := m.match(method, path, matchOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func testRoute() *Route {
+	r := NewRoute()
+	r.GET("/", dummyHandler)
+	r.GET("/hello", dummyHandler)
+	r.GET("/hello/world", dummyHandler)
+	r.GET("/hello/worlds", dummyHandler)
+	r.GET("/users", dummyHandler)
+	r.GET("/users/new", dummyHandler)
+	r.GET("/users/:id<int>", dummyHandler)
+	r.POST("/users/:id<int>", testHandler("handler"))
+	r.GET("/users/:uid<uuid>", dummyHandler)
+	r.GET("/users/:name", dummyHandler)
+	r.POST("/users/:id<int>/posts", dummyHandler)
+	r.GET("/users/:name/posts", dummyHandler)
+	r.ALL("/files/*", dummyHandler)
+	r.GET("/files/:name.:ext", dummyHandler)
+	r.GET("/assets/*filepath", dummyHandler)
+	r.GET("/v:version<int>/status", dummyHandler)
+	r.GET("/posts/:page?", dummyHandler)
+
+	for i := 0; i < 20; i++ {
+		r.GET(fmt.Sprintf("/static/%d/page", i), dummyHandler)
+	}
+
+	r.Middleware("/", testMiddleware("root"))
+	r.Middleware("/users", testMiddleware("users"))
+	r.MethodMiddleware(POST, "/users/:id<int>", testMiddleware("post"))
+	return r
+}
+
+func testMiddleware(name string) Middleware {
+	return func(ctx context.Context, req *Req, resp *Resp, next Handler) error {
+		resp.Write([]byte(name + ","))
+		return next(ctx, req, resp)
+	}
+}
+
+func testHandler(name string) Handler {
+	return func(ctx context.Context, req *Req, resp *Resp) error {
+		resp.Write([]byte(name))
+		return nil
+	}
+}
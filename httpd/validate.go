@@ -0,0 +1,490 @@
+package httpd
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Validator validates a non-nil field value with a rule param, returns an error message,
+// i.e. "must be a valid email address", or an empty string. The parent is the struct which contains
+// the field, it is used in cross-field rules.
+type Validator func(field reflect.Value, param string, parent reflect.Value) string
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]Validator{
+		"min":      validateMin,
+		"max":      validateMax,
+		"len":      validateLen,
+		"oneof":    validateOneOf,
+		"email":    validateEmail,
+		"url":      validateURL,
+		"uuid":     validateUUID,
+		"eqfield":  compareField("eqfield"),
+		"nefield":  compareField("nefield"),
+		"gtfield":  compareField("gtfield"),
+		"gtefield": compareField("gtefield"),
+		"ltfield":  compareField("ltfield"),
+		"ltefield": compareField("ltefield"),
+	}
+)
+
+// validationTypes caches parsed validation rules of struct types, see typeRules.
+var validationTypes sync.Map // map[reflect.Type][]fieldRules
+
+// fieldRules are parsed validation rules of a struct field.
+type fieldRules struct {
+	index int
+	name  string
+	in    string
+	rules []validationRule
+}
+
+type validationRule struct {
+	name  string
+	param string
+}
+
+// RegisterValidator adds a validation rule or replaces the current one, i.e.
+//
+//	httpd.RegisterValidator("even", func(field reflect.Value, param string, parent reflect.Value) string {
+//		if field.Int()%2 != 0 {
+//			return "must be even"
+//		}
+//		return ""
+//	})
+func RegisterValidator(name string, v Validator) {
+	if v == nil {
+		panic("router: Nil validator")
+	}
+	if name == "" || strings.ContainsAny(name, ",= ") {
+		panic(fmt.Sprintf("router: Invalid validator name %q", name))
+	}
+
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = v
+}
+
+// Validate validates a struct or a struct pointer by validate tags, returns a BadRequestError which
+// lists all invalid fields, i.e.
+//
+//	type User struct {
+//		Name     string `json:"name" validate:"required,min=1,max=100"`
+//		Role     string `json:"role" validate:"omitempty,oneof=admin user"`
+//		Email    string `json:"email" validate:"required,email"`
+//		Password string `json:"password" validate:"required,min=8"`
+//		Confirm  string `json:"confirm" validate:"eqfield=Password"`
+//	}
+//
+// Rules are separated by commas, params follow equal signs. Built-in rules are required, required_with,
+// required_without, omitempty, min, max and len (lengths of strings, slices and maps, values of numbers), oneof
+// (space-separated values), email, url, uuid, eqfield, nefield, gtfield, gtefield, ltfield and ltefield
+// (comparisons to other fields of the same struct). Other rules are skipped for nil pointers and,
+// when a field has the omitempty rule, for empty values.
+// Nested structs, struct pointers and slices of structs are validated recursively. Fields are named
+// by their json, path, query, form or header tags, nested fields are joined with dots, i.e. "address.city".
+//
+// Rules are parsed once per struct type including nested struct types, Validate panics on unknown rules,
+// unknown fields and invalid size params when it first validates a type.
+// Validate is called by Req.Bind and Req.DecodeJSON.
+func Validate(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []FieldError
+	fields = validateStruct(rv, "", fields)
+	if len(fields) > 0 {
+		return NewFieldsError(fields...)
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, result []FieldError) []FieldError {
+	for _, f := range typeRules(v.Type()) {
+		field := v.Field(f.index)
+		name := f.name
+		if prefix != "" && name != "" {
+			name = prefix + "." + name
+		} else if name == "" {
+			name = prefix
+		}
+
+		if len(f.rules) > 0 {
+			result = validateRules(field, f.rules, v, name, f.in, result)
+		}
+		result = validateNested(field, name, result)
+	}
+	return result
+}
+
+// validateRules applies rules to a field, stops at the first failed rule.
+func validateRules(field reflect.Value, rules []validationRule, parent reflect.Value, name string, in string,
+	result []FieldError) []FieldError {
+
+	value := indirect(field)
+	empty := !value.IsValid() || value.IsZero()
+	skip := !value.IsValid()
+	for _, rule := range rules {
+		if rule.name == "omitempty" {
+			skip = skip || empty
+		}
+	}
+
+	for _, rule := range rules {
+		message := ""
+		switch rule.name {
+		case "omitempty":
+		case "required":
+			if empty {
+				message = "is required"
+			}
+		case "required_with":
+			if empty && !isEmpty(parent.FieldByName(rule.param)) {
+				message = fmt.Sprintf("is required when %v is present", rule.param)
+			}
+		case "required_without":
+			if empty && isEmpty(parent.FieldByName(rule.param)) {
+				message = fmt.Sprintf("is required when %v is missing", rule.param)
+			}
+		default:
+			if !skip {
+				validator, _ := lookupValidator(rule.name)
+				message = validator(value, rule.param, parent)
+			}
+		}
+
+		if message != "" {
+			code := rule.name
+			if strings.HasPrefix(code, "required") {
+				code = "required"
+			}
+			return append(result, FieldError{Field: name, In: in, Code: code, Message: message})
+		}
+	}
+	return result
+}
+
+// typeRules returns cached validation rules of struct fields, parses the rules of a struct type
+// and its nested struct types when the type is first validated.
+func typeRules(t reflect.Type) []fieldRules {
+	if cached, ok := validationTypes.Load(t); ok {
+		return cached.([]fieldRules)
+	}
+
+	var result []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, in := fieldName(f)
+		if f.Anonymous && name == f.Name {
+			name = ""
+		}
+
+		rules := fieldRules{index: i, name: name, in: in}
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			rules.rules = parseRules(t, tag)
+		}
+		result = append(result, rules)
+	}
+
+	// Store the rules before parsing nested types, they can be recursive.
+	validationTypes.Store(t, result)
+	for _, f := range result {
+		if nested, ok := nestedValidationType(t.Field(f.index).Type); ok {
+			typeRules(nested)
+		}
+	}
+	return result
+}
+
+// parseRules parses comma-separated rules of a struct field, panics on unknown rules,
+// unknown fields in cross-field rules and invalid size params.
+func parseRules(t reflect.Type, tag string) []validationRule {
+	var result []validationRule
+	for _, s := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(s), "=")
+		switch name {
+		case "required", "omitempty":
+		case "required_with", "required_without", "eqfield", "nefield", "gtfield", "gtefield", "ltfield", "ltefield":
+			if _, ok := t.FieldByName(param); !ok {
+				panic(fmt.Sprintf("router: Unknown validation field %q in %v", param, t))
+			}
+		case "min", "max", "len":
+			if _, err := strconv.ParseFloat(param, 64); err != nil {
+				panic(fmt.Sprintf("router: Invalid validation param %q", param))
+			}
+		}
+
+		if !strings.HasPrefix(name, "required") && name != "omitempty" {
+			if _, ok := lookupValidator(name); !ok {
+				panic(fmt.Sprintf("router: Unknown validation rule %q", name))
+			}
+		}
+		result = append(result, validationRule{name: name, param: param})
+	}
+	return result
+}
+
+// nestedValidationType returns a struct type of a struct, a struct pointer or a slice of them.
+func nestedValidationType(t reflect.Type) (reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct && t != timeType
+}
+
+func lookupValidator(name string) (Validator, bool) {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+
+	validator, ok := validators[name]
+	return validator, ok
+}
+
+// validateNested validates nested structs, struct pointers and slices of structs.
+func validateNested(field reflect.Value, name string, result []FieldError) []FieldError {
+	value := indirect(field)
+	if !value.IsValid() {
+		return result
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if value.Type() != timeType {
+			result = validateStruct(value, name, result)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if elem := indirect(value.Index(i)); elem.IsValid() && elem.Kind() == reflect.Struct {
+				result = validateStruct(elem, fmt.Sprintf("%v[%d]", name, i), result)
+			}
+		}
+	}
+	return result
+}
+
+// fieldName returns a field name and source from its tags.
+func fieldName(f reflect.StructField) (name string, in string) {
+	if tag, ok := f.Tag.Lookup("json"); ok {
+		if name, _, _ = strings.Cut(tag, ","); name != "" && name != "-" {
+			return name, ""
+		}
+	}
+	for _, source := range bindSources {
+		if name := f.Tag.Get(source); name != "" && name != "-" {
+			if source == "path" {
+				source = "param"
+			}
+			return name, source
+		}
+	}
+	return f.Name, ""
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isEmpty(v reflect.Value) bool {
+	v = indirect(v)
+	return !v.IsValid() || v.IsZero()
+}
+
+func validateMin(field reflect.Value, param string, parent reflect.Value) string {
+	if compareSize(field, param) < 0 {
+		return "must " + sizeMessage(field, "at least", param)
+	}
+	return ""
+}
+
+func validateMax(field reflect.Value, param string, parent reflect.Value) string {
+	if compareSize(field, param) > 0 {
+		return "must " + sizeMessage(field, "at most", param)
+	}
+	return ""
+}
+
+func validateLen(field reflect.Value, param string, parent reflect.Value) string {
+	if compareSize(field, param) != 0 {
+		return "must " + sizeMessage(field, "exactly", param)
+	}
+	return ""
+}
+
+// compareSize compares a string length, a slice or map length or a number to a param.
+func compareSize(field reflect.Value, param string) int {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("router: Invalid validation param %q", param))
+	}
+
+	var size float64
+	switch field.Kind() {
+	case reflect.String:
+		size = float64(utf8.RuneCountInString(field.String()))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		size = float64(field.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(field.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(field.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = field.Float()
+	default:
+		panic(fmt.Sprintf("router: Size validation of unsupported type %v", field.Type()))
+	}
+
+	switch {
+	case size < limit:
+		return -1
+	case size > limit:
+		return 1
+	}
+	return 0
+}
+
+func sizeMessage(field reflect.Value, comparison string, param string) string {
+	switch field.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("be %v %v characters long", comparison, param)
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return fmt.Sprintf("contain %v %v items", comparison, param)
+	}
+	if comparison == "exactly" {
+		return "be " + param
+	}
+	return fmt.Sprintf("be %v %v", comparison, param)
+}
+
+func validateOneOf(field reflect.Value, param string, parent reflect.Value) string {
+	allowed := strings.Fields(param)
+	s := fmt.Sprint(field.Interface())
+	for _, a := range allowed {
+		if s == a {
+			return ""
+		}
+	}
+	return "must be one of " + strings.Join(allowed, ", ")
+}
+
+func validateEmail(field reflect.Value, param string, parent reflect.Value) string {
+	s := field.String()
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return "must be a valid email address"
+	}
+	return ""
+}
+
+func validateURL(field reflect.Value, param string, parent reflect.Value) string {
+	u, err := url.ParseRequestURI(field.String())
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "must be a valid URL"
+	}
+	return ""
+}
+
+func validateUUID(field reflect.Value, param string, parent reflect.Value) string {
+	if !uuidRe.MatchString(field.String()) {
+		return "must be a UUID"
+	}
+	return ""
+}
+
+// compareField returns a cross-field comparison validator, i.e. eqfield=Password.
+func compareField(rule string) Validator {
+	return func(field reflect.Value, param string, parent reflect.Value) string {
+		other := indirect(parent.FieldByName(param))
+		if rule == "eqfield" || rule == "nefield" {
+			equal := other.IsValid() && reflect.DeepEqual(field.Interface(), other.Interface())
+			switch {
+			case rule == "eqfield" && !equal:
+				return "must be equal to " + param
+			case rule == "nefield" && equal:
+				return "must not be equal to " + param
+			}
+			return ""
+		}
+
+		if !other.IsValid() {
+			return ""
+		}
+		c := compareValues(field, other)
+		switch {
+		case rule == "gtfield" && c <= 0:
+			return "must be greater than " + param
+		case rule == "gtefield" && c < 0:
+			return "must be greater than or equal to " + param
+		case rule == "ltfield" && c >= 0:
+			return "must be less than " + param
+		case rule == "ltefield" && c > 0:
+			return "must be less than or equal to " + param
+		}
+		return ""
+	}
+}
+
+// compareValues compares numbers, strings, times and durations of the same type.
+func compareValues(a reflect.Value, b reflect.Value) int {
+	if a.Type() != b.Type() {
+		panic(fmt.Sprintf("router: Cannot compare %v and %v in validation", a.Type(), b.Type()))
+	}
+
+	if a.Type() == timeType {
+		t0, t1 := a.Interface().(time.Time), b.Interface().(time.Time)
+		switch {
+		case t0.Before(t1):
+			return -1
+		case t0.After(t1):
+			return 1
+		}
+		return 0
+	}
+
+	switch a.Kind() {
+	case reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareFloats(float64(a.Int()), float64(b.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareFloats(float64(a.Uint()), float64(b.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return compareFloats(a.Float(), b.Float())
+	}
+	panic(fmt.Sprintf("router: Cannot compare %v in validation", a.Type()))
+}
+
+func compareFloats(a float64, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,146 @@
+package httpd
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateUser struct {
+	Name     string            `json:"name" validate:"required,min=2,max=5"`
+	Role     string            `json:"role" validate:"omitempty,oneof=admin user"`
+	Email    string            `json:"email" validate:"omitempty,email"`
+	Site     string            `json:"site" validate:"omitempty,url"`
+	Age      int               `json:"age" validate:"min=18"`
+	Tags     []string          `json:"tags" validate:"max=2"`
+	Password string            `json:"password"`
+	Confirm  string            `json:"confirm" validate:"eqfield=Password"`
+	Phone    string            `json:"phone" validate:"required_without=Email"`
+	Start    int               `json:"start"`
+	End      int               `json:"end" validate:"gtefield=Start"`
+	Limit    int               `query:"limit" validate:"max=100"`
+	Address  validateAddress   `json:"address"`
+	Other    []validateAddress `json:"other"`
+}
+
+func TestValidate(t *testing.T) {
+	user := validateUser{
+		Name:     "John",
+		Role:     "user",
+		Email:    "john@example.com",
+		Site:     "https://example.com",
+		Age:      30,
+		Password: "secret",
+		Confirm:  "secret",
+		Start:    1,
+		End:      2,
+		Address:  validateAddress{City: "London"},
+	}
+	assert.Nil(t, Validate(&user))
+}
+
+func TestValidate__should_list_invalid_fields(t *testing.T) {
+	user := validateUser{
+		Name:     "Johnathan",
+		Role:     "root",
+		Email:    "john",
+		Site:     "example",
+		Age:      10,
+		Tags:     []string{"a", "b", "c"},
+		Password: "secret",
+		Confirm:  "secret1",
+		Start:    2,
+		End:      1,
+		Limit:    1000,
+		Other:    []validateAddress{{}},
+	}
+
+	err := Validate(user)
+	assert.Equal(t, NewFieldsError(
+		FieldError{Field: "name", Code: "max", Message: "must be at most 5 characters long"},
+		FieldError{Field: "role", Code: "oneof", Message: "must be one of admin, user"},
+		FieldError{Field: "email", Code: "email", Message: "must be a valid email address"},
+		FieldError{Field: "site", Code: "url", Message: "must be a valid URL"},
+		FieldError{Field: "age", Code: "min", Message: "must be at least 18"},
+		FieldError{Field: "tags", Code: "max", Message: "must contain at most 2 items"},
+		FieldError{Field: "confirm", Code: "eqfield", Message: "must be equal to Password"},
+		FieldError{Field: "end", Code: "gtefield", Message: "must be greater than or equal to Start"},
+		FieldError{Field: "limit", In: "query", Code: "max", Message: "must be at most 100"},
+		FieldError{Field: "address.city", Code: "required", Message: "is required"},
+		FieldError{Field: "other[0].city", Code: "required", Message: "is required"},
+	), err)
+
+	err = Validate(validateUser{Name: "John", Age: 30, Address: validateAddress{City: "London"}})
+	assert.EqualError(t, err, `Bad request: Missing "phone"`)
+}
+
+func TestValidate__should_apply_rules_to_zero_values(t *testing.T) {
+	type adult struct {
+		Age   int  `json:"age" validate:"min=18"`
+		Limit *int `json:"limit" validate:"min=1"`
+		Score int  `json:"score" validate:"omitempty,min=10"`
+	}
+
+	err := Validate(adult{})
+	assert.Equal(t, NewFieldsError(FieldError{Field: "age", Code: "min", Message: "must be at least 18"}), err)
+
+	zero := 0
+	err = Validate(adult{Age: 18, Limit: &zero})
+	assert.Equal(t, NewFieldsError(FieldError{Field: "limit", Code: "min", Message: "must be at least 1"}), err)
+}
+
+func TestRegisterValidator(t *testing.T) {
+	RegisterValidator("test_even", func(field reflect.Value, param string, parent reflect.Value) string {
+		if field.Int()%2 != 0 {
+			return "must be even"
+		}
+		return ""
+	})
+	t.Cleanup(func() {
+		validatorsMu.Lock()
+		defer validatorsMu.Unlock()
+		delete(validators, "test_even")
+	})
+
+	v := struct {
+		N int `json:"n" validate:"test_even"`
+	}{N: 3}
+	assert.EqualError(t, Validate(v), `Bad request: Invalid "n", must be even`)
+}
+
+func TestValidate__should_panic_on_invalid_rules_when_type_is_first_used(t *testing.T) {
+	assert.PanicsWithValue(t, `router: Unknown validation rule "unknown"`, func() {
+		Validate(struct {
+			N int `validate:"unknown"`
+		}{})
+	})
+	assert.PanicsWithValue(t, `router: Invalid validation param "x"`, func() {
+		Validate(struct {
+			N int `validate:"min=x"`
+		}{})
+	})
+
+	// Rules of empty fields and nil nested structs are checked too.
+	assert.PanicsWithValue(t, `router: Unknown validation field "Pasword" in struct { Confirm string "validate:\"eqfield=Pasword\"" }`,
+		func() {
+			Validate(struct {
+				Nested *struct {
+					Confirm string `validate:"eqfield=Pasword"`
+				}
+			}{})
+		})
+}
+
+func TestReq_DecodeJSON__should_validate(t *testing.T) {
+	httpReq := newBodyRequest(http.MethodPost, "/", "application/json", `{"city": ""}`)
+	req := newReq(NewRouter(nil), httpReq, nil)
+
+	err := req.DecodeJSON(&validateAddress{})
+	assert.Equal(t, NewFieldsError(FieldError{Field: "city", Code: "required", Message: "is required"}), err)
+}
@@ -0,0 +1,216 @@
+package httpd
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value is a named request value, i.e. a path param or a form value. Its parsers return BadRequestErrors
+// which name the value, i.e. `Bad request: Invalid param "id", must be an integer`, empty values are missing.
+// Must parsers abort handlers with the errors, i.e. with 400 responses.
+//
+//	id, err := req.Params.Value("id").Int64()
+//	limit, err := req.FormField("limit").Default("10").Int()
+//	order := req.FormField("order").Default("asc").MustEnum("asc", "desc")
+type Value struct {
+	Source string // Value source, i.e. "param" or "form".
+	Name   string
+	Raw    string
+	Exists bool
+}
+
+var uuidRe = compileConstraint("uuid")
+
+// abort is a panic value which aborts a handler with an error, see Value.
+type abort struct {
+	err error
+}
+
+// Value returns a path param value.
+func (p Params) Value(name string) Value {
+	v, ok := p[name]
+	return Value{Source: "param", Name: name, Raw: v, Exists: ok}
+}
+
+// FormField returns a form value, see http.Request.FormValue.
+func (r *Req) FormField(key string) Value {
+	v := r.FormValue(key)
+	_, ok := r.Form[key]
+	return Value{Source: "form", Name: key, Raw: v, Exists: ok}
+}
+
+// Default returns a value with a default when the value is missing or empty.
+func (v Value) Default(def string) Value {
+	if v.Raw == "" {
+		v.Raw = def
+		v.Exists = true
+	}
+	return v
+}
+
+// Required returns a value or an error when it is missing or empty.
+func (v Value) Required() (string, error) {
+	if v.Raw == "" {
+		return "", NewFieldsError(FieldError{Field: v.Name, In: v.Source, Code: "required", Message: "is required"})
+	}
+	return v.Raw, nil
+}
+
+func (v Value) Int() (int, error) {
+	i, err := v.parseInt(0)
+	return int(i), err
+}
+
+func (v Value) Int32() (int32, error) {
+	i, err := v.parseInt(32)
+	return int32(i), err
+}
+
+func (v Value) Int64() (int64, error) {
+	return v.parseInt(64)
+}
+
+func (v Value) Uint() (uint, error) {
+	i, err := v.parseUint(0)
+	return uint(i), err
+}
+
+func (v Value) Uint64() (uint64, error) {
+	return v.parseUint(64)
+}
+
+// Float64 parses a finite number, NaN and infinities are invalid.
+func (v Value) Float64() (float64, error) {
+	s, err := v.Required()
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, v.invalid("a number")
+	}
+	return f, nil
+}
+
+// Bool parses 1, t, T, TRUE, true, True, 0, f, F, FALSE, false, False.
+func (v Value) Bool() (bool, error) {
+	s, err := v.Required()
+	if err != nil {
+		return false, err
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, v.invalid("a boolean")
+	}
+	return b, nil
+}
+
+// UUID returns a lowercase UUID.
+func (v Value) UUID() (string, error) {
+	s, err := v.Required()
+	if err != nil {
+		return "", err
+	}
+
+	if !uuidRe.MatchString(s) {
+		return "", v.invalid("a UUID")
+	}
+	return strings.ToLower(s), nil
+}
+
+// Time parses an RFC 3339 time, i.e. 2006-01-02T15:04:05Z.
+func (v Value) Time() (time.Time, error) {
+	s, err := v.Required()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, v.invalid("an RFC 3339 time")
+	}
+	return t, nil
+}
+
+// Duration parses a duration, i.e. 300ms or 1h30m.
+func (v Value) Duration() (time.Duration, error) {
+	s, err := v.Required()
+	if err != nil {
+		return 0, err
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, v.invalid("a duration")
+	}
+	return d, nil
+}
+
+// Enum returns a value or an error when the value is not one of the allowed values.
+func (v Value) Enum(allowed ...string) (string, error) {
+	s, err := v.Required()
+	if err != nil {
+		return "", err
+	}
+
+	for _, a := range allowed {
+		if s == a {
+			return s, nil
+		}
+	}
+	return "", v.invalid("one of " + strings.Join(allowed, ", "))
+}
+
+func (v Value) MustRequired() string              { s, err := v.Required(); must(err); return s }
+func (v Value) MustInt() int                      { i, err := v.Int(); must(err); return i }
+func (v Value) MustInt32() int32                  { i, err := v.Int32(); must(err); return i }
+func (v Value) MustInt64() int64                  { i, err := v.Int64(); must(err); return i }
+func (v Value) MustUint() uint                    { i, err := v.Uint(); must(err); return i }
+func (v Value) MustUint64() uint64                { i, err := v.Uint64(); must(err); return i }
+func (v Value) MustFloat64() float64              { f, err := v.Float64(); must(err); return f }
+func (v Value) MustBool() bool                    { b, err := v.Bool(); must(err); return b }
+func (v Value) MustUUID() string                  { s, err := v.UUID(); must(err); return s }
+func (v Value) MustTime() time.Time               { t, err := v.Time(); must(err); return t }
+func (v Value) MustDuration() time.Duration       { d, err := v.Duration(); must(err); return d }
+func (v Value) MustEnum(allowed ...string) string { s, err := v.Enum(allowed...); must(err); return s }
+
+func (v Value) parseInt(bitSize int) (int64, error) {
+	s, err := v.Required()
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.ParseInt(s, 10, bitSize)
+	if err != nil {
+		return 0, v.invalid("an integer")
+	}
+	return i, nil
+}
+
+func (v Value) parseUint(bitSize int) (uint64, error) {
+	s, err := v.Required()
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.ParseUint(s, 10, bitSize)
+	if err != nil {
+		return 0, v.invalid("a non-negative integer")
+	}
+	return i, nil
+}
+
+func (v Value) invalid(expected string) error {
+	return NewFieldsError(FieldError{Field: v.Name, In: v.Source, Code: "invalid", Message: "must be " + expected})
+}
+
+// must aborts a handler with an error, the router recovers it and handles the error.
+func must(err error) {
+	if err != nil {
+		panic(abort{err})
+	}
+}
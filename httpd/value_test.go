@@ -0,0 +1,93 @@
+package httpd
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValue(t *testing.T) {
+	p := Params{
+		"id":       "123",
+		"name":     "abc",
+		"negative": "-1",
+		"price":    "1.5",
+		"flag":     "true",
+		"uid":      "6BA7B810-9DAD-11D1-80B4-00C04FD430C8",
+		"time":     "2020-01-02T03:04:05Z",
+		"timeout":  "1m30s",
+		"order":    "desc",
+	}
+
+	i, err := p.Value("id").Int()
+	assert.Nil(t, err)
+	assert.Equal(t, 123, i)
+
+	_, err = p.Value("name").Int()
+	assert.EqualError(t, err, `Bad request: Invalid param "name", must be an integer`)
+
+	_, err = p.Value("unknown").Int64()
+	assert.EqualError(t, err, `Bad request: Missing param "unknown"`)
+
+	_, err = p.Value("negative").Uint()
+	assert.EqualError(t, err, `Bad request: Invalid param "negative", must be a non-negative integer`)
+
+	f, err := p.Value("price").Float64()
+	assert.Nil(t, err)
+	assert.Equal(t, 1.5, f)
+
+	for _, raw := range []string{"NaN", "Inf", "-Inf", "+inf", "1e400"} {
+		_, err = Value{Source: "param", Name: "price", Raw: raw, Exists: true}.Float64()
+		assert.EqualError(t, err, `Bad request: Invalid param "price", must be a number`, raw)
+	}
+
+	b, err := p.Value("flag").Bool()
+	assert.Nil(t, err)
+	assert.True(t, b)
+
+	uid, err := p.Value("uid").UUID()
+	assert.Nil(t, err)
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", uid)
+
+	tm, err := p.Value("time").Time()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), tm)
+
+	d, err := p.Value("timeout").Duration()
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Second, d)
+
+	_, err = p.Value("order").Enum("asc")
+	assert.EqualError(t, err, `Bad request: Invalid param "order", must be one of asc`)
+
+	limit, err := p.Value("limit").Default("10").Int()
+	assert.Nil(t, err)
+	assert.Equal(t, 10, limit)
+
+	_, err = p.Value("name").Default("10").Int()
+	assert.NotNil(t, err)
+}
+
+func TestValue_Must__should_abort_handler_with_bad_request(t *testing.T) {
+	r := NewRouter(nil)
+	r.GET("/users/:id", func(ctx context.Context, req *Req, resp *Resp) error {
+		id := req.Params.Value("id").MustInt64()
+		limit := req.FormField("limit").Default("10").MustInt()
+		return resp.JSON(map[string]int64{"id": id, "limit": int64(limit)})
+	})
+
+	w := serve(r, http.MethodGet, "/users/1?limit=5")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id": 1, "limit": 5}`, w.Body.String())
+
+	w = serve(r, http.MethodGet, "/users/abc")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `Invalid param \"id\", must be an integer`)
+
+	w = serve(r, http.MethodGet, "/users/1?limit=x")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `Invalid form \"limit\", must be an integer`)
+}